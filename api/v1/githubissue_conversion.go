@@ -0,0 +1,220 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	issuesv2 "dvir.io/githubissue/api/v2"
+	"dvir.io/githubissue/internal/repourl"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// TemplateInputsAnnotation keeps the template inputs of a v1 issue whose description is read from a ConfigMap or
+// a Secret. v2 only renders inline templates, so they are kept aside to survive a round trip through v2
+const TemplateInputsAnnotation = "issues.dvir.io/v1-template-inputs"
+
+// RepoURLAnnotation keeps the repo url of a v1 issue that v2 cannot rebuild from its host, owner and name, such as
+// urls with a .git suffix or a path below the repository
+const RepoURLAnnotation = "issues.dvir.io/v1-repo"
+
+const defaultRepoHost = "github.com"
+
+var _ conversion.Convertible = &GithubIssue{}
+
+// ConvertTo converts this GithubIssue to the hub version (v2)
+func (src *GithubIssue) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*issuesv2.GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a v2 GithubIssue but got %T", dstRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	host, owner, name, err := repourl.Split(src.Spec.Repo)
+	if err != nil {
+		return err
+	}
+	dst.Spec.Repo = issuesv2.RepositoryRef{Host: host, Owner: owner, Name: name}
+	if repourl.Join(host, owner, name) != src.Spec.Repo {
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[RepoURLAnnotation] = src.Spec.Repo
+	} else {
+		delete(dst.Annotations, RepoURLAnnotation)
+	}
+	dst.Spec.Title = src.Spec.Title
+	dst.Spec.Body = issuesv2.BodySource{Inline: src.Spec.Description}
+	if src.Spec.DescriptionFrom != nil {
+		dst.Spec.Body.ConfigMapRef = src.Spec.DescriptionFrom.ConfigMapKeyRef
+		dst.Spec.Body.SecretRef = src.Spec.DescriptionFrom.SecretKeyRef
+	}
+	if len(src.Spec.TemplateInputs) > 0 {
+		if src.Spec.DescriptionFrom == nil {
+			dst.Spec.Body = issuesv2.BodySource{Template: &issuesv2.BodyTemplate{
+				Text:   src.Spec.Description,
+				Inputs: convertInputsTo(src.Spec.TemplateInputs),
+			}}
+		} else {
+			raw, err := json.Marshal(src.Spec.TemplateInputs)
+			if err != nil {
+				return fmt.Errorf("failed encoding template inputs: %v", err.Error())
+			}
+			if dst.Annotations == nil {
+				dst.Annotations = map[string]string{}
+			}
+			dst.Annotations[TemplateInputsAnnotation] = string(raw)
+		}
+	}
+	dst.Spec.Labels = src.Spec.Labels
+	dst.Spec.Assignees = src.Spec.Assignees
+	dst.Spec.Number = src.Spec.Number
+	dst.Spec.MirrorComments = src.Spec.MirrorComments
+	dst.Spec.MilestoneRef = (*issuesv2.MilestoneReference)(src.Spec.MilestoneRef)
+	dst.Spec.State = src.Spec.State
+	dst.Spec.StateReason = src.Spec.StateReason
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.SyncInterval = src.Spec.SyncInterval
+	for _, action := range src.Spec.OnPullRequestMerged {
+		dst.Spec.OnPullRequestMerged = append(dst.Spec.OnPullRequestMerged, issuesv2.RemediationAction{
+			RolloutRestart: (*issuesv2.RolloutRestartAction)(action.RolloutRestart),
+			CreateJob:      (*issuesv2.CreateJobAction)(action.CreateJob),
+			DeleteIssue:    action.DeleteIssue,
+		})
+	}
+
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.Number = src.Status.Number
+	dst.Status.URL = src.Status.URL
+	dst.Status.RenderedTitle = src.Status.RenderedTitle
+	dst.Status.RenderedBody = src.Status.RenderedDescription
+	for _, comment := range src.Status.Comments {
+		dst.Status.Comments = append(dst.Status.Comments, issuesv2.IssueComment(comment))
+	}
+	dst.Status.CommentCount = src.Status.CommentCount
+	dst.Status.Reactions = (*issuesv2.IssueReactions)(src.Status.Reactions)
+	dst.Status.LastSyncTime = src.Status.LastSyncTime
+	for _, pr := range src.Status.LinkedPRs {
+		dst.Status.LinkedPRs = append(dst.Status.LinkedPRs, issuesv2.LinkedPullRequest(pr))
+	}
+	dst.Status.RemediatedAt = src.Status.RemediatedAt
+	dst.Status.Plan = (*issuesv2.IssuePlan)(src.Status.Plan)
+	dst.Status.LastHandledReconcileAt = src.Status.LastHandledReconcileAt
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *GithubIssue) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*issuesv2.GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a v2 GithubIssue but got %T", srcRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	host := src.Spec.Repo.Host
+	if host == "" {
+		host = defaultRepoHost
+	}
+	dst.Spec.Repo = repourl.Join(host, src.Spec.Repo.Owner, src.Spec.Repo.Name)
+	// The original url is only restored while it still points to the repository of the hub
+	if raw, ok := dst.Annotations[RepoURLAnnotation]; ok {
+		if rawHost, rawOwner, rawName, err := repourl.Split(raw); err == nil && repourl.Join(rawHost, rawOwner, rawName) == dst.Spec.Repo {
+			dst.Spec.Repo = raw
+		}
+		delete(dst.Annotations, RepoURLAnnotation)
+	}
+	dst.Spec.Title = src.Spec.Title
+	dst.Spec.Description = src.Spec.Body.Inline
+	if src.Spec.Body.ConfigMapRef != nil || src.Spec.Body.SecretRef != nil {
+		dst.Spec.DescriptionFrom = &DescriptionSource{
+			ConfigMapKeyRef: src.Spec.Body.ConfigMapRef,
+			SecretKeyRef:    src.Spec.Body.SecretRef,
+		}
+	}
+	if src.Spec.Body.Template != nil {
+		dst.Spec.Description = src.Spec.Body.Template.Text
+		dst.Spec.TemplateInputs = convertInputsFrom(src.Spec.Body.Template.Inputs)
+	}
+	if raw, ok := dst.Annotations[TemplateInputsAnnotation]; ok {
+		var inputs []TemplateInput
+		if err := json.Unmarshal([]byte(raw), &inputs); err != nil {
+			return fmt.Errorf("invalid %s annotation: %v", TemplateInputsAnnotation, err.Error())
+		}
+		if dst.Spec.DescriptionFrom != nil {
+			dst.Spec.TemplateInputs = inputs
+		}
+		delete(dst.Annotations, TemplateInputsAnnotation)
+	}
+	dst.Spec.Labels = src.Spec.Labels
+	dst.Spec.Assignees = src.Spec.Assignees
+	dst.Spec.Number = src.Spec.Number
+	dst.Spec.MirrorComments = src.Spec.MirrorComments
+	dst.Spec.MilestoneRef = (*MilestoneReference)(src.Spec.MilestoneRef)
+	dst.Spec.State = src.Spec.State
+	dst.Spec.StateReason = src.Spec.StateReason
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.SyncInterval = src.Spec.SyncInterval
+	for _, action := range src.Spec.OnPullRequestMerged {
+		dst.Spec.OnPullRequestMerged = append(dst.Spec.OnPullRequestMerged, RemediationAction{
+			RolloutRestart: (*RolloutRestartAction)(action.RolloutRestart),
+			CreateJob:      (*CreateJobAction)(action.CreateJob),
+			DeleteIssue:    action.DeleteIssue,
+		})
+	}
+
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.Number = src.Status.Number
+	dst.Status.URL = src.Status.URL
+	dst.Status.RenderedTitle = src.Status.RenderedTitle
+	dst.Status.RenderedDescription = src.Status.RenderedBody
+	for _, comment := range src.Status.Comments {
+		dst.Status.Comments = append(dst.Status.Comments, IssueComment(comment))
+	}
+	dst.Status.CommentCount = src.Status.CommentCount
+	dst.Status.Reactions = (*IssueReactions)(src.Status.Reactions)
+	dst.Status.LastSyncTime = src.Status.LastSyncTime
+	for _, pr := range src.Status.LinkedPRs {
+		dst.Status.LinkedPRs = append(dst.Status.LinkedPRs, LinkedPullRequest(pr))
+	}
+	dst.Status.RemediatedAt = src.Status.RemediatedAt
+	dst.Status.Plan = (*IssuePlan)(src.Status.Plan)
+	dst.Status.LastHandledReconcileAt = src.Status.LastHandledReconcileAt
+	return nil
+}
+
+func convertInputsTo(inputs []TemplateInput) []issuesv2.TemplateInput {
+	converted := make([]issuesv2.TemplateInput, 0, len(inputs))
+	for _, input := range inputs {
+		converted = append(converted, issuesv2.TemplateInput{
+			Name:      input.Name,
+			ObjectRef: issuesv2.TemplateObjectReference(input.ObjectRef),
+		})
+	}
+	return converted
+}
+
+func convertInputsFrom(inputs []issuesv2.TemplateInput) []TemplateInput {
+	converted := make([]TemplateInput, 0, len(inputs))
+	for _, input := range inputs {
+		converted = append(converted, TemplateInput{
+			Name:      input.Name,
+			ObjectRef: TemplateObjectReference(input.ObjectRef),
+		})
+	}
+	return converted
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"time"
+
+	issuesv2 "dvir.io/githubissue/api/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GithubIssue conversion", func() {
+	It("converts v1 to v2 and back", func() {
+		issue := &GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+			Spec: GithubIssueSpec{
+				Repo:           "https://github.com/test/test",
+				Title:          "a title",
+				Description:    "a description",
+				Labels:         []string{"bug"},
+				Number:         4,
+				MirrorComments: 2,
+				MilestoneRef:   &MilestoneReference{Name: "v1.0"},
+				State:          "closed",
+				StateReason:    "not_planned",
+				Suspend:        true,
+				SyncInterval:   &metav1.Duration{Duration: time.Hour},
+				OnPullRequestMerged: []RemediationAction{
+					{RolloutRestart: &RolloutRestartAction{Kind: "Deployment", Name: "web"}},
+					{DeleteIssue: true},
+				},
+			},
+			Status: GithubIssueStatus{
+				Number:       4,
+				URL:          "https://github.com/test/test/issues/4",
+				Comments:     []IssueComment{{Author: "octocat", Body: "on it"}},
+				CommentCount: 1,
+				Reactions:    &IssueReactions{TotalCount: 1, Eyes: 1},
+				LinkedPRs:    []LinkedPullRequest{{Number: 5, URL: "https://github.com/test/test/pull/5", State: "open"}},
+				Plan:         &IssuePlan{Action: "edit", Diff: []string{`title: "a" -> "a title"`}},
+
+				LastHandledReconcileAt: "2024-05-01T10:00:00Z",
+			},
+		}
+		hub := &issuesv2.GithubIssue{}
+		Expect(issue.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.Repo).To(Equal(issuesv2.RepositoryRef{Host: "github.com", Owner: "test", Name: "test"}))
+		Expect(hub.Spec.Body.Inline).To(Equal("a description"))
+		Expect(hub.Status.URL).To(Equal(issue.Status.URL))
+
+		roundTrip := &GithubIssue{}
+		Expect(roundTrip.ConvertFrom(hub)).To(Succeed())
+		Expect(roundTrip.Spec).To(Equal(issue.Spec))
+		Expect(roundTrip.Status).To(Equal(issue.Status))
+	})
+
+	It("maps body references to descriptionFrom", func() {
+		hub := &issuesv2.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+			Spec: issuesv2.GithubIssueSpec{
+				Repo:  issuesv2.RepositoryRef{Host: "github.com", Owner: "test", Name: "test"},
+				Title: "a title",
+				Body: issuesv2.BodySource{Inline: "footer", ConfigMapRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "runbook"},
+					Key:                  "body.md",
+				}},
+			},
+		}
+		spoke := &GithubIssue{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Repo).To(Equal("https://github.com/test/test"))
+		Expect(spoke.Spec.Description).To(Equal("footer"))
+		Expect(spoke.Spec.DescriptionFrom.ConfigMapKeyRef.Name).To(Equal("runbook"))
+
+		roundTrip := &issuesv2.GithubIssue{}
+		Expect(spoke.ConvertTo(roundTrip)).To(Succeed())
+		Expect(roundTrip.Spec).To(Equal(hub.Spec))
+	})
+
+	It("maps inline templates to templateInputs", func() {
+		hub := &issuesv2.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+			Spec: issuesv2.GithubIssueSpec{
+				Repo:  issuesv2.RepositoryRef{Host: "github.com", Owner: "test", Name: "test"},
+				Title: "{{ .Deployment.metadata.name }} is down",
+				Body: issuesv2.BodySource{Template: &issuesv2.BodyTemplate{
+					Text: "{{ .Deployment.status.unavailableReplicas }} unavailable replicas",
+					Inputs: []issuesv2.TemplateInput{{
+						Name:      "Deployment",
+						ObjectRef: issuesv2.TemplateObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+					}},
+				}},
+			},
+		}
+		spoke := &GithubIssue{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Description).To(Equal(hub.Spec.Body.Template.Text))
+		Expect(spoke.Spec.TemplateInputs).To(HaveLen(1))
+
+		roundTrip := &issuesv2.GithubIssue{}
+		Expect(spoke.ConvertTo(roundTrip)).To(Succeed())
+		Expect(roundTrip.Spec).To(Equal(hub.Spec))
+	})
+
+	It("keeps the template inputs of referenced descriptions through a round trip", func() {
+		issue := &GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+			Spec: GithubIssueSpec{
+				Repo:  "https://github.com/test/test",
+				Title: "a title",
+				DescriptionFrom: &DescriptionSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "runbook"},
+					Key:                  "body.md",
+				}},
+				TemplateInputs: []TemplateInput{{
+					Name:      "Deployment",
+					ObjectRef: TemplateObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+				}},
+			},
+		}
+		hub := &issuesv2.GithubIssue{}
+		Expect(issue.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Annotations).To(HaveKey(TemplateInputsAnnotation))
+
+		roundTrip := &GithubIssue{}
+		Expect(roundTrip.ConvertFrom(hub)).To(Succeed())
+		Expect(roundTrip.Spec).To(Equal(issue.Spec))
+		Expect(roundTrip.Annotations).ToNot(HaveKey(TemplateInputsAnnotation))
+	})
+
+	DescribeTable("keeps repo urls that v2 cannot rebuild through a round trip",
+		func(repo string) {
+			issue := &GithubIssue{
+				ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+				Spec:       GithubIssueSpec{Repo: repo, Title: "a title"},
+			}
+			hub := &issuesv2.GithubIssue{}
+			Expect(issue.ConvertTo(hub)).To(Succeed())
+			Expect(hub.Spec.Repo).To(Equal(issuesv2.RepositoryRef{Host: "github.com", Owner: "test", Name: "test"}))
+			Expect(hub.Annotations).To(HaveKeyWithValue(RepoURLAnnotation, repo))
+
+			roundTrip := &GithubIssue{}
+			Expect(roundTrip.ConvertFrom(hub)).To(Succeed())
+			Expect(roundTrip.Spec).To(Equal(issue.Spec))
+			Expect(roundTrip.Annotations).ToNot(HaveKey(RepoURLAnnotation))
+		},
+		Entry("a .git suffix", "https://github.com/test/test.git"),
+		Entry("a path below the repository", "https://github.com/test/test/issues"),
+		Entry("a trailing slash", "https://github.com/test/test/"),
+	)
+
+	It("drops the kept repo url once the hub points to another repository", func() {
+		hub := &issuesv2.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "issue",
+				Namespace:   "default",
+				Annotations: map[string]string{RepoURLAnnotation: "https://github.com/test/test.git"},
+			},
+			Spec: issuesv2.GithubIssueSpec{
+				Repo:  issuesv2.RepositoryRef{Host: "github.com", Owner: "test", Name: "other"},
+				Title: "a title",
+			},
+		}
+		spoke := &GithubIssue{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Repo).To(Equal("https://github.com/test/other"))
+		Expect(spoke.Annotations).ToNot(HaveKey(RepoURLAnnotation))
+	})
+})
@@ -17,9 +17,166 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// SourceLabel is set on the GithubIssue objects created by the operator itself, holding what filed them
+const SourceLabel = "issues.dvir.io/source"
+
+// ReconcileRequestedAnnotation forces a reconcile of a GithubIssue when changed, it holds the time it was requested at.
+// The reconciler records the value it handled in status.lastHandledReconcileAt
+const ReconcileRequestedAnnotation = "issues.dvir.io/reconcile-requested-at"
+
+// DryRunAnnotation set to "true" makes the reconciler plan the GitHub mutations of a GithubIssue without making them
+const DryRunAnnotation = "issues.dvir.io/dry-run"
+
+// PausedAnnotation set to "true" stops the operator from writing to the GitHub issue of a GithubIssue, like spec.suspend
+const PausedAnnotation = "issues.dvir.io/paused"
+
+// SuspendedNamespaceLabel set to "true" on a namespace suspends the writes to GitHub of every GithubIssue,
+// GithubIssueComment, GithubLabelSet and GithubMilestone in it
+const SuspendedNamespaceLabel = "issues.dvir.io/suspended"
+
+// ReportFailuresAnnotation opts a Pod, Job or Deployment in to having its failures filed as issues, holding the
+// repository to file them in
+const ReportFailuresAnnotation = "issues.dvir.io/report-failures-to"
+
+// DescriptionSource selects a key of a ConfigMap or a Secret holding the description of the issue
+// +kubebuilder:validation:XValidation:rule="has(self.configMapKeyRef) != has(self.secretKeyRef)",message="exactly one of configMapKeyRef and secretKeyRef must be set"
+type DescriptionSource struct {
+	// +kubebuilder:validation:Optional
+	//ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the issue
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//SecretKeyRef selects a key of a Secret in the namespace of the issue
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// TemplateObjectReference references an object in the namespace of the issue, or a cluster scoped object
+type TemplateObjectReference struct {
+	// +kubebuilder:validation:Required
+	//APIVersion of the object, e.g. apps/v1
+	APIVersion string `json:"apiVersion"`
+
+	// +kubebuilder:validation:Required
+	//Kind of the object, e.g. Deployment
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Required
+	//Name of the object
+	Name string `json:"name"`
+}
+
+// TemplateInput exposes an object to the title and description templates
+type TemplateInput struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	//Name the object is exposed as, e.g. Deployment for {{ .Deployment.metadata.name }}. Issue is reserved for the GithubIssue itself
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	//ObjectRef of the object to expose
+	ObjectRef TemplateObjectReference `json:"objectRef"`
+}
+
+// IssueComment is a comment of the GitHub issue mirrored into the status
+type IssueComment struct {
+	//Author GitHub login of the comment author
+	Author string `json:"author,omitempty"`
+
+	//CreatedAt time the comment was posted
+	CreatedAt metav1.Time `json:"createdAt,omitempty"`
+
+	//Body of the comment, truncated
+	Body string `json:"body,omitempty"`
+}
+
+// IssueReactions counts the reactions on the GitHub issue
+type IssueReactions struct {
+	TotalCount int `json:"totalCount,omitempty"`
+	PlusOne    int `json:"plusOne,omitempty"`
+	MinusOne   int `json:"minusOne,omitempty"`
+	Laugh      int `json:"laugh,omitempty"`
+	Confused   int `json:"confused,omitempty"`
+	Heart      int `json:"heart,omitempty"`
+	Hooray     int `json:"hooray,omitempty"`
+	Rocket     int `json:"rocket,omitempty"`
+	Eyes       int `json:"eyes,omitempty"`
+}
+
+// RolloutRestartAction restarts the pods of a workload in the namespace of the issue, like kubectl rollout restart
+type RolloutRestartAction struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
+	//Kind of the workload
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Required
+	//Name of the workload
+	Name string `json:"name"`
+}
+
+// CreateJobAction creates a Job from the job template of a CronJob in the namespace of the issue, like
+// kubectl create job --from=cronjob/<name>
+type CreateJobAction struct {
+	// +kubebuilder:validation:Required
+	//FromCronJob name of the CronJob whose job template is used, it is usually suspended
+	FromCronJob string `json:"fromCronJob"`
+}
+
+// RemediationAction is run once a pull request linked to the issue is merged. Exactly one of its fields must be set
+// +kubebuilder:validation:XValidation:rule="[has(self.rolloutRestart), has(self.createJob), has(self.deleteIssue)].filter(x, x).size() == 1",message="exactly one of rolloutRestart, createJob and deleteIssue must be set"
+type RemediationAction struct {
+	// +kubebuilder:validation:Optional
+	//RolloutRestart restarts a workload
+	RolloutRestart *RolloutRestartAction `json:"rolloutRestart,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//CreateJob creates a Job from a CronJob
+	CreateJob *CreateJobAction `json:"createJob,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//DeleteIssue deletes this GithubIssue, it always runs after the other actions
+	DeleteIssue bool `json:"deleteIssue,omitempty"`
+}
+
+// LinkedPullRequest is a pull request referencing the issue
+type LinkedPullRequest struct {
+	//Number of the pull request
+	Number int `json:"number"`
+
+	//URL of the pull request
+	URL string `json:"url"`
+
+	//State of the pull request, open, closed or merged
+	State string `json:"state"`
+
+	//MergedAt time the pull request was merged
+	MergedAt *metav1.Time `json:"mergedAt,omitempty"`
+
+	//Closes is set for pull requests of the repository of the issue that close it, with a closing keyword or a
+	//link to the issue. Only these run the onPullRequestMerged actions
+	Closes bool `json:"closes,omitempty"`
+}
+
+// IssuePlan is the GitHub mutation a dry run would have made
+type IssuePlan struct {
+	//Action planned, none, create, edit or close
+	Action string `json:"action"`
+
+	//Diff has a line per changed field of the issue
+	Diff []string `json:"diff,omitempty"`
+}
+
+// MilestoneReference references a GithubMilestone in the namespace of the issue
+type MilestoneReference struct {
+	// +kubebuilder:validation:Required
+	//Name of the GithubMilestone
+	Name string `json:"name"`
+}
+
 // GithubIssueSpec defines the desired state of GithubIssue
 type GithubIssueSpec struct {
 	// +kubebuilder:validation:Required
@@ -36,12 +193,105 @@ type GithubIssueSpec struct {
 	// +kubebuilder:validation:Type=string
 	//Description string that goes in the body of the issue
 	Description string `json:"description,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//DescriptionFrom reads the body of the issue from a ConfigMap or a Secret, that whoever sets it must be allowed to get.
+	//When description is also set, it is appended to the referenced content
+	DescriptionFrom *DescriptionSource `json:"descriptionFrom,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//TemplateInputs objects exposed to the title and description, Secrets cannot be exposed. When set, title and description are rendered as Go templates. Missing fields render as "<no value>", use default to pick a fallback
+	TemplateInputs []TemplateInput `json:"templateInputs,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Labels to set on the issue. Labels added on GitHub are left untouched when empty
+	Labels []string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	//Assignees GitHub logins to assign to the issue
+	Assignees []string `json:"assignees,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	//Number of an existing issue to bind to instead of searching the repository by title
+	Number int `json:"number,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=20
+	//MirrorComments number of latest comments of the issue mirrored into the status. The IssueHasNewComments condition is only set when mirroring
+	MirrorComments int `json:"mirrorComments,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//MilestoneRef sets the milestone of the issue to the one created by a GithubMilestone in the same repository
+	MilestoneRef *MilestoneReference `json:"milestoneRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=open;closed
+	//State the issue is kept in. When empty, the issue can be closed and reopened on GitHub freely
+	State string `json:"state,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=completed;not_planned
+	//StateReason reported to GitHub when closing the issue
+	StateReason string `json:"stateReason,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	//OnPullRequestMerged actions run once, when the first pull request of the repository closing the issue is merged
+	OnPullRequestMerged []RemediationAction `json:"onPullRequestMerged,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Suspend stops the operator from writing to the GitHub issue, its status is still refreshed
+	Suspend bool `json:"suspend,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//SyncInterval between refreshes of the issue from GitHub, e.g. 30s for a hot issue or 1h for an archived one.
+	//Defaults to the manager --sync-interval and is raised to its --min-sync-interval
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // GithubIssueStatus defines the observed state of GithubIssue
 type GithubIssueStatus struct {
 	// Conditions is a slice of conditions on the issue, such as if it is open or closed or if it has an attached PR
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Number of the GitHub issue this object is bound to. Once set, the repo can no longer be changed
+	Number int `json:"number,omitempty"`
+
+	// URL of the GitHub issue this object is bound to
+	URL string `json:"url,omitempty"`
+
+	// RenderedTitle is the title rendered from the templateInputs
+	RenderedTitle string `json:"renderedTitle,omitempty"`
+
+	// RenderedDescription is the description rendered from the templateInputs
+	RenderedDescription string `json:"renderedDescription,omitempty"`
+
+	// Comments are the latest comments of the issue, newest first
+	Comments []IssueComment `json:"comments,omitempty"`
+
+	// CommentCount is the total number of comments on the issue
+	CommentCount int `json:"commentCount,omitempty"`
+
+	// Reactions on the issue
+	Reactions *IssueReactions `json:"reactions,omitempty"`
+
+	// LastSyncTime is when the comments were last fetched from GitHub
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// LinkedPRs are the pull requests referencing the issue
+	LinkedPRs []LinkedPullRequest `json:"linkedPRs,omitempty"`
+
+	// RemediatedAt is when the onPullRequestMerged actions completed
+	RemediatedAt *metav1.Time `json:"remediatedAt,omitempty"`
+
+	// Plan is the GitHub mutation planned by the last dry run, only set in dry run mode
+	Plan *IssuePlan `json:"plan,omitempty"`
+
+	// LastHandledReconcileAt is the value of the issues.dvir.io/reconcile-requested-at annotation last reconciled
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
 }
 
 // +kubebuilder:object:root=true
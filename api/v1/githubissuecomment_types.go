@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IssueReference references a GithubIssue in the namespace of the comment
+type IssueReference struct {
+	// +kubebuilder:validation:Required
+	//Name of the GithubIssue
+	Name string `json:"name"`
+}
+
+// GithubIssueCommentSpec defines the desired state of GithubIssueComment
+// +kubebuilder:validation:XValidation:rule="has(self.issueRef) != (has(self.repo) && has(self.number))",message="exactly one of issueRef and repo with number must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.repo) == has(self.number)",message="repo and number must be set together"
+type GithubIssueCommentSpec struct {
+	// +kubebuilder:validation:Optional
+	//IssueRef the GithubIssue to comment on. The comment is posted once the issue is bound to a GitHub issue
+	IssueRef *IssueReference `json:"issueRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https:\/\/github\.com\/[\w.-]+\/[\w.-]+`
+	//Repo GitHub url of the repository of the issue to comment on, used with number
+	Repo string `json:"repo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	//Number of the issue to comment on, used with repo
+	Number int `json:"number,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	//Body of the comment
+	Body string `json:"body"`
+}
+
+// GithubIssueCommentStatus defines the observed state of GithubIssueComment
+type GithubIssueCommentStatus struct {
+	// Conditions is a slice of conditions on the comment, such as if it is in sync with GitHub
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// CommentID of the GitHub comment managed by this object
+	CommentID int64 `json:"commentID,omitempty"`
+
+	// URL of the GitHub comment managed by this object
+	URL string `json:"url,omitempty"`
+
+	// Repo of the issue the comment was posted on, kept to delete the comment once the referenced issue is gone
+	Repo string `json:"repo,omitempty"`
+
+	// IssueNumber of the issue the comment was posted on
+	IssueNumber int `json:"issueNumber,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Issue",type=integer,JSONPath=`.status.issueNumber`
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+// GithubIssueComment is the Schema for the githubissuecomments API
+type GithubIssueComment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueCommentSpec   `json:"spec,omitempty"`
+	Status GithubIssueCommentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueCommentList contains a list of GithubIssueComment
+type GithubIssueCommentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueComment `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueComment{}, &GithubIssueCommentList{})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubIssueDefaultsSpec defines the conventions injected into matching GithubIssue objects
+type GithubIssueDefaultsSpec struct {
+	// +kubebuilder:validation:Optional
+	//NamespaceSelector selects the namespaces whose issues get these defaults. An empty selector matches every namespace
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//RepoOwners restricts the defaults to repos owned by these users or organizations. Empty matches every owner
+	RepoOwners []string `json:"repoOwners,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Repos restricts the defaults to these owner/repo repositories. Empty matches every repository
+	Repos []string `json:"repos,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Labels added to every matching issue
+	Labels []string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Assignees added to every matching issue
+	Assignees []string `json:"assignees,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//TitlePrefix prepended to the title of matching issues
+	TitlePrefix string `json:"titlePrefix,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//BodyFooter Go template appended to the description of matching issues.
+	//It can reference {{ .Cluster }}, {{ .Namespace }} and {{ .Name }} of the GithubIssue
+	BodyFooter string `json:"bodyFooter,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//SyncInterval set on matching issues that do not set one
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// GithubIssueDefaults is the Schema for the githubissuedefaults API.
+// When several objects match an issue their labels and assignees are merged, and the title prefix, body footer and
+// sync interval are taken from the most specific one: matching the repo, then both namespace and owner, then owner,
+// then namespace.
+type GithubIssueDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GithubIssueDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueDefaultsList contains a list of GithubIssueDefaults
+type GithubIssueDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueDefaults{}, &GithubIssueDefaultsList{})
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubIssuePolicySpec defines what the GithubIssue objects of the selected namespaces may do
+type GithubIssuePolicySpec struct {
+	// +kubebuilder:validation:Optional
+	//NamespaceSelector selects the namespaces the policy applies to. An empty selector matches every namespace
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Repos are the owner/repo globs, such as my-org/* or my-org/service-?, that issues may target. Empty allows every repository
+	Repos []string `json:"repos,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Labels are the label globs that issues may set. Empty allows every label
+	Labels []string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	//MaxOpenIssues is the maximum number of issues each selected namespace may keep open, 0 means no limit.
+	//The oldest issues keep their place, newer ones are denied until older ones are closed or deleted
+	MaxOpenIssues int `json:"maxOpenIssues,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// GithubIssuePolicy is the Schema for the githubissuepolicies API.
+// Namespaces selected by no policy are not restricted. When several policies select a namespace, an issue is allowed
+// when any one of them allows its repo, its labels and one more open issue.
+type GithubIssuePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GithubIssuePolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssuePolicyList contains a list of GithubIssuePolicy
+type GithubIssuePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssuePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssuePolicy{}, &GithubIssuePolicyList{})
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduleLabel is set on the GithubIssue children of a GithubIssueSchedule, holding the name of the schedule
+const ScheduleLabel = "issues.dvir.io/schedule"
+
+// ScheduledAtAnnotation is set on the GithubIssue children of a GithubIssueSchedule, holding the time they were scheduled for
+const ScheduledAtAnnotation = "issues.dvir.io/scheduled-at"
+
+// ConcurrencyPolicy describes how an issue is filed when the previous one is still open
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent files the issue even if the previous one is still open
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+
+	// ForbidConcurrent skips the tick while the previous issue is still open
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+
+	// ReplaceConcurrent closes the previous issues before filing the new one
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// ScheduledIssueTemplate is the issue filed at every tick.
+// Title and description are Go templates that can reference {{ .Date }}, formatted as 2006-01-02, and {{ .Time }}, the scheduled time
+type ScheduledIssueTemplate struct {
+	// +kubebuilder:validation:Optional
+	//Metadata of the GithubIssue children. The issues.dvir.io/schedule label is always set
+	Metadata IssueTemplateMetadata `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https:\/\/github\.com\/[\w.-]+\/[\w.-]+`
+	//Repo GitHub url of the repository where the issues are filed
+	Repo string `json:"repo"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	//Title of the issues, e.g. Monthly access review {{ .Time.Format "January 2006" }}
+	Title string `json:"title"`
+
+	// +kubebuilder:validation:Optional
+	//Description of the issues
+	Description string `json:"description,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Labels to set on the issues
+	Labels []string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	//Assignees GitHub logins to assign to the issues
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+// GithubIssueScheduleSpec defines the desired state of GithubIssueSchedule
+type GithubIssueScheduleSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	//Schedule in Cron format, see https://en.wikipedia.org/wiki/Cron
+	Schedule string `json:"schedule"`
+
+	// +kubebuilder:validation:Optional
+	//TimeZone name of the schedule, e.g. Europe/Berlin. Defaults to the time zone of the manager
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	//StartingDeadlineSeconds deadline for filing an issue that missed its scheduled time
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Allow
+	//ConcurrencyPolicy Allow, Forbid or Replace the previous issue when it is still open
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Suspend stops filing new issues
+	Suspend bool `json:"suspend,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=3
+	//ClosedHistoryLimit number of closed issues whose GithubIssue is kept
+	ClosedHistoryLimit *int32 `json:"closedHistoryLimit,omitempty"`
+
+	// +kubebuilder:validation:Required
+	//Template of the issue filed at every tick
+	Template ScheduledIssueTemplate `json:"template"`
+}
+
+// GithubIssueScheduleStatus defines the observed state of GithubIssueSchedule
+type GithubIssueScheduleStatus struct {
+	// Conditions is a slice of conditions on the schedule, such as if the last tick was skipped
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Active are the GithubIssue children whose issue is still open
+	Active []corev1.ObjectReference `json:"active,omitempty"`
+
+	// LastScheduleTime is the last time an issue was filed
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+// GithubIssueSchedule is the Schema for the githubissueschedules API
+type GithubIssueSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueScheduleSpec   `json:"spec,omitempty"`
+	Status GithubIssueScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueScheduleList contains a list of GithubIssueSchedule
+type GithubIssueScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueSchedule{}, &GithubIssueScheduleList{})
+}
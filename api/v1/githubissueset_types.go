@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RepoAnnotation is read from the namespaces selected by a namespace generator, holding the GitHub url of the repository of the namespace
+const RepoAnnotation = "issues.dvir.io/repo"
+
+// IssueSetLabel is set on the GithubIssue children of a GithubIssueSet, holding the name of the set
+const IssueSetLabel = "issues.dvir.io/issue-set"
+
+// ListGenerator generates a fixed list of repositories
+type ListGenerator struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Pattern=`^https:\/\/github\.com\/[\w.-]+\/[\w.-]+`
+	//Repos GitHub urls of the repositories
+	Repos []string `json:"repos"`
+}
+
+// NamespaceGenerator generates the repositories of the namespaces matching a label selector, read from their issues.dvir.io/repo annotation
+type NamespaceGenerator struct {
+	// +kubebuilder:validation:Required
+	//Selector of the namespaces
+	Selector metav1.LabelSelector `json:"selector"`
+}
+
+// OrgGenerator generates every repository of a GitHub organization
+type OrgGenerator struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[\w.-]+$`
+	//Org name of the organization
+	Org string `json:"org"`
+
+	// +kubebuilder:validation:Optional
+	//Topics only generates the repositories having all of these topics
+	Topics []string `json:"topics,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//IncludeArchived also generates archived repositories
+	IncludeArchived bool `json:"includeArchived,omitempty"`
+}
+
+// IssueSetGenerator generates repositories. Exactly one generator must be set
+// +kubebuilder:validation:XValidation:rule="[has(self.list), has(self.namespaces), has(self.org)].filter(x, x).size() == 1",message="exactly one of list, namespaces and org must be set"
+type IssueSetGenerator struct {
+	// +kubebuilder:validation:Optional
+	//List of repositories
+	List *ListGenerator `json:"list,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Namespaces whose issues.dvir.io/repo annotation holds a repository
+	Namespaces *NamespaceGenerator `json:"namespaces,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Org repositories of a GitHub organization
+	Org *OrgGenerator `json:"org,omitempty"`
+}
+
+// IssueTemplate is the issue created in every generated repository.
+// Title and description are Go templates that can reference {{ .Repo }}, {{ .Owner }} and {{ .Name }} of the repository
+type IssueTemplate struct {
+	// +kubebuilder:validation:Optional
+	//Labels set on the GithubIssue children, in addition to issues.dvir.io/issue-set
+	Metadata IssueTemplateMetadata `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	//Title of the issues
+	Title string `json:"title"`
+
+	// +kubebuilder:validation:Optional
+	//Description of the issues
+	Description string `json:"description,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Labels to set on the issues
+	Labels []string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	//Assignees GitHub logins to assign to the issues
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+// IssueTemplateMetadata is the metadata of the GithubIssue children
+type IssueTemplateMetadata struct {
+	// +kubebuilder:validation:Optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// GithubIssueSetSpec defines the desired state of GithubIssueSet
+type GithubIssueSetSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	//Generators of the repositories to file the issue in. Repositories generated more than once get a single issue
+	Generators []IssueSetGenerator `json:"generators"`
+
+	// +kubebuilder:validation:Required
+	//Template of the issue filed in every repository
+	Template IssueTemplate `json:"template"`
+}
+
+// GithubIssueSetStatus defines the observed state of GithubIssueSet
+type GithubIssueSetStatus struct {
+	// Conditions is a slice of conditions on the set, such as if the repositories were generated
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Repos is the number of generated repositories
+	Repos int `json:"repos,omitempty"`
+
+	// Open is the number of open issues
+	Open int `json:"open,omitempty"`
+
+	// Closed is the number of closed issues
+	Closed int `json:"closed,omitempty"`
+
+	// WithPR is the number of issues linked to a pull request
+	WithPR int `json:"withPR,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Repos",type=integer,JSONPath=`.status.repos`
+// +kubebuilder:printcolumn:name="Open",type=integer,JSONPath=`.status.open`
+// +kubebuilder:printcolumn:name="Closed",type=integer,JSONPath=`.status.closed`
+// +kubebuilder:printcolumn:name="PRs",type=integer,JSONPath=`.status.withPR`
+// GithubIssueSet is the Schema for the githubissuesets API.
+// It owns a GithubIssue per generated repository. Issues of repositories that are no longer generated are deleted, closing them
+type GithubIssueSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueSetSpec   `json:"spec,omitempty"`
+	Status GithubIssueSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueSetList contains a list of GithubIssueSet
+type GithubIssueSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueSet{}, &GithubIssueSetList{})
+}
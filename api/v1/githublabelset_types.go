@@ -0,0 +1,101 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LabelSpec defines a label of the repository
+type LabelSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=50
+	//Name of the label
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{6}$`
+	//Color hex code of the label, without the leading #
+	Color string `json:"color"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=100
+	//Description of the label
+	Description string `json:"description,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//PreviousNames of the label. An existing label with one of these names is renamed, keeping it on the issues it is set on
+	PreviousNames []string `json:"previousNames,omitempty"`
+}
+
+// GithubLabelSetSpec defines the desired state of GithubLabelSet
+type GithubLabelSetSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https:\/\/github\.com\/[\w.-]+\/[\w.-]+`
+	//Repo GitHub url of the repository whose labels are managed
+	Repo string `json:"repo"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	//Labels the repository should have
+	Labels []LabelSpec `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//Prune deletes the labels of the repository that are not listed
+	Prune bool `json:"prune,omitempty"`
+}
+
+// GithubLabelSetStatus defines the observed state of GithubLabelSet
+type GithubLabelSetStatus struct {
+	// Conditions is a slice of conditions on the label set, such as if the repository labels are in sync
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Labels are the names of the labels last synced to the repository
+	Labels []string `json:"labels,omitempty"`
+
+	// Pruned are the names of the labels deleted from the repository by the last sync
+	Pruned []string `json:"pruned,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Repo",type=string,JSONPath=`.spec.repo`
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="LabelsSynced")].status`
+// GithubLabelSet is the Schema for the githublabelsets API.
+// Labels are left on the repository when the GithubLabelSet is deleted
+type GithubLabelSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubLabelSetSpec   `json:"spec,omitempty"`
+	Status GithubLabelSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubLabelSetList contains a list of GithubLabelSet
+type GithubLabelSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubLabelSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubLabelSet{}, &GithubLabelSetList{})
+}
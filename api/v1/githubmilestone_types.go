@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubMilestoneSpec defines the desired state of GithubMilestone
+type GithubMilestoneSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https:\/\/github\.com\/[\w.-]+\/[\w.-]+`
+	//Repo GitHub url of the repository where the milestone should be created
+	Repo string `json:"repo"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	//Title of the milestone. An existing milestone with the same title is adopted
+	Title string `json:"title"`
+
+	// +kubebuilder:validation:Optional
+	//Description of the milestone
+	Description string `json:"description,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	//DueOn date of the milestone. GitHub only keeps the date
+	DueOn *metav1.Time `json:"dueOn,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=open;closed
+	// +kubebuilder:default=open
+	//State of the milestone
+	State string `json:"state,omitempty"`
+}
+
+// GithubMilestoneStatus defines the observed state of GithubMilestone
+type GithubMilestoneStatus struct {
+	// Conditions is a slice of conditions on the milestone, such as if it is in sync with GitHub
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Number of the GitHub milestone this object is bound to
+	Number int `json:"number,omitempty"`
+
+	// URL of the GitHub milestone this object is bound to
+	URL string `json:"url,omitempty"`
+
+	// OpenIssues is the number of open issues in the milestone
+	OpenIssues int `json:"openIssues,omitempty"`
+
+	// ClosedIssues is the number of closed issues in the milestone
+	ClosedIssues int `json:"closedIssues,omitempty"`
+
+	// PercentComplete is the share of closed issues in the milestone
+	PercentComplete int `json:"percentComplete,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Title",type=string,JSONPath=`.spec.title`
+// +kubebuilder:printcolumn:name="Due",type=string,JSONPath=`.spec.dueOn`
+// +kubebuilder:printcolumn:name="Open",type=integer,JSONPath=`.status.openIssues`
+// +kubebuilder:printcolumn:name="Closed",type=integer,JSONPath=`.status.closedIssues`
+// +kubebuilder:printcolumn:name="Complete",type=integer,JSONPath=`.status.percentComplete`
+// GithubMilestone is the Schema for the githubmilestones API.
+// The milestone is closed when the GithubMilestone is deleted
+type GithubMilestone struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubMilestoneSpec   `json:"spec,omitempty"`
+	Status GithubMilestoneStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubMilestoneList contains a list of GithubMilestone
+type GithubMilestoneList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubMilestone `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubMilestone{}, &GithubMilestoneList{})
+}
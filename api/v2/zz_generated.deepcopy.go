@@ -0,0 +1,420 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BodySource) DeepCopyInto(out *BodySource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(BodyTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BodySource.
+func (in *BodySource) DeepCopy() *BodySource {
+	if in == nil {
+		return nil
+	}
+	out := new(BodySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BodyTemplate) DeepCopyInto(out *BodyTemplate) {
+	*out = *in
+	if in.Inputs != nil {
+		in, out := &in.Inputs, &out.Inputs
+		*out = make([]TemplateInput, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BodyTemplate.
+func (in *BodyTemplate) DeepCopy() *BodyTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(BodyTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CreateJobAction) DeepCopyInto(out *CreateJobAction) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CreateJobAction.
+func (in *CreateJobAction) DeepCopy() *CreateJobAction {
+	if in == nil {
+		return nil
+	}
+	out := new(CreateJobAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssue) DeepCopyInto(out *GithubIssue) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssue.
+func (in *GithubIssue) DeepCopy() *GithubIssue {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubIssue) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueList) DeepCopyInto(out *GithubIssueList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GithubIssue, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueList.
+func (in *GithubIssueList) DeepCopy() *GithubIssueList {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubIssueList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueSpec) DeepCopyInto(out *GithubIssueSpec) {
+	*out = *in
+	out.Repo = in.Repo
+	in.Body.DeepCopyInto(&out.Body)
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Assignees != nil {
+		in, out := &in.Assignees, &out.Assignees
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MilestoneRef != nil {
+		in, out := &in.MilestoneRef, &out.MilestoneRef
+		*out = new(MilestoneReference)
+		**out = **in
+	}
+	if in.OnPullRequestMerged != nil {
+		in, out := &in.OnPullRequestMerged, &out.OnPullRequestMerged
+		*out = make([]RemediationAction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueSpec.
+func (in *GithubIssueSpec) DeepCopy() *GithubIssueSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueStatus) DeepCopyInto(out *GithubIssueStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Comments != nil {
+		in, out := &in.Comments, &out.Comments
+		*out = make([]IssueComment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Reactions != nil {
+		in, out := &in.Reactions, &out.Reactions
+		*out = new(IssueReactions)
+		**out = **in
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LinkedPRs != nil {
+		in, out := &in.LinkedPRs, &out.LinkedPRs
+		*out = make([]LinkedPullRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RemediatedAt != nil {
+		in, out := &in.RemediatedAt, &out.RemediatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(IssuePlan)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueStatus.
+func (in *GithubIssueStatus) DeepCopy() *GithubIssueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssueComment) DeepCopyInto(out *IssueComment) {
+	*out = *in
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssueComment.
+func (in *IssueComment) DeepCopy() *IssueComment {
+	if in == nil {
+		return nil
+	}
+	out := new(IssueComment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssuePlan) DeepCopyInto(out *IssuePlan) {
+	*out = *in
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssuePlan.
+func (in *IssuePlan) DeepCopy() *IssuePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(IssuePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssueReactions) DeepCopyInto(out *IssueReactions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssueReactions.
+func (in *IssueReactions) DeepCopy() *IssueReactions {
+	if in == nil {
+		return nil
+	}
+	out := new(IssueReactions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkedPullRequest) DeepCopyInto(out *LinkedPullRequest) {
+	*out = *in
+	if in.MergedAt != nil {
+		in, out := &in.MergedAt, &out.MergedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkedPullRequest.
+func (in *LinkedPullRequest) DeepCopy() *LinkedPullRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkedPullRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MilestoneReference) DeepCopyInto(out *MilestoneReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MilestoneReference.
+func (in *MilestoneReference) DeepCopy() *MilestoneReference {
+	if in == nil {
+		return nil
+	}
+	out := new(MilestoneReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationAction) DeepCopyInto(out *RemediationAction) {
+	*out = *in
+	if in.RolloutRestart != nil {
+		in, out := &in.RolloutRestart, &out.RolloutRestart
+		*out = new(RolloutRestartAction)
+		**out = **in
+	}
+	if in.CreateJob != nil {
+		in, out := &in.CreateJob, &out.CreateJob
+		*out = new(CreateJobAction)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationAction.
+func (in *RemediationAction) DeepCopy() *RemediationAction {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryRef) DeepCopyInto(out *RepositoryRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryRef.
+func (in *RepositoryRef) DeepCopy() *RepositoryRef {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutRestartAction) DeepCopyInto(out *RolloutRestartAction) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutRestartAction.
+func (in *RolloutRestartAction) DeepCopy() *RolloutRestartAction {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutRestartAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateInput) DeepCopyInto(out *TemplateInput) {
+	*out = *in
+	out.ObjectRef = in.ObjectRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateInput.
+func (in *TemplateInput) DeepCopy() *TemplateInput {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateObjectReference) DeepCopyInto(out *TemplateObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateObjectReference.
+func (in *TemplateObjectReference) DeepCopy() *TemplateObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
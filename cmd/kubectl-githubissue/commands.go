@@ -0,0 +1,304 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func runList(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("list", true)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	var opts []client.ListOption
+	if !kf.allNamespaces {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	issues := &issuesv1.GithubIssueList{}
+	if err := c.List(ctx, issues, opts...); err != nil {
+		return err
+	}
+	if len(issues.Items) == 0 {
+		fmt.Fprintln(os.Stderr, "No issues found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if kf.allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tSTATE\tPR\tURL")
+	for _, issue := range issues.Items {
+		if kf.allNamespaces {
+			fmt.Fprintf(w, "%s\t", issue.Namespace)
+		}
+		url := issue.Status.URL
+		if url == "" {
+			url = "<none>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Name, issueState(&issue), pullRequests(&issue), url)
+	}
+	return w.Flush()
+}
+
+// issueState reads the state of the GitHub issue from the IssueIsOpen condition
+func issueState(issue *issuesv1.GithubIssue) string {
+	condition := meta.FindStatusCondition(issue.Status.Conditions, "IssueIsOpen")
+	switch {
+	case condition == nil:
+		return "Pending"
+	case condition.Status == metav1.ConditionTrue:
+		return "Open"
+	default:
+		return "Closed"
+	}
+}
+
+// pullRequests summarizes the linked pull requests, e.g. #9 (merged)
+func pullRequests(issue *issuesv1.GithubIssue) string {
+	var prs []string
+	for _, pr := range issue.Status.LinkedPRs {
+		prs = append(prs, fmt.Sprintf("#%d (%s)", pr.Number, pr.State))
+	}
+	if len(prs) == 0 {
+		return "<none>"
+	}
+	return strings.Join(prs, ",")
+}
+
+func runOpen(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("open", false)
+	positional, err := parse(fs, args, "<name>")
+	if err != nil {
+		return err
+	}
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	issue := &issuesv1.GithubIssue{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: positional[0]}, issue); err != nil {
+		return err
+	}
+	if issue.Status.URL == "" {
+		return fmt.Errorf("githubissue %s is not bound to a GitHub issue yet", issue.Name)
+	}
+	return openBrowser(issue.Status.URL)
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed opening %s: %v", url, err.Error())
+	}
+	return nil
+}
+
+func runAdopt(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("adopt", false)
+	name := fs.String("name", "", "Name of the GithubIssue, defaults to <owner>-<repo>-<number>.")
+	positional, err := parse(fs, args, "<repo>#<number>")
+	if err != nil {
+		return err
+	}
+	repoURL, number, err := parseIssueRef(positional[0])
+	if err != nil {
+		return err
+	}
+	owner, repo, err := repourl.Parse(repoURL)
+	if err != nil {
+		return err
+	}
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	ghIssue, _, err := gitHubClient().Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed fetching issue %s#%d: %v", repoURL, number, err.Error())
+	}
+	if ghIssue.IsPullRequest() {
+		return fmt.Errorf("%s#%d is a pull request", repoURL, number)
+	}
+	issue := adoptedIssue(namespace, *name, repoURL, ghIssue)
+	if err := c.Create(ctx, issue); err != nil {
+		return err
+	}
+	fmt.Printf("githubissue/%s adopted %s\n", issue.Name, ghIssue.GetHTMLURL())
+	return nil
+}
+
+// parseIssueRef parses <repo>#<number>, where repo is a repository url or owner/repo
+func parseIssueRef(ref string) (string, int, error) {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid issue %q, expected <repo>#<number>", ref)
+	}
+	number, err := strconv.Atoi(ref[i+1:])
+	if err != nil || number < 1 {
+		return "", 0, fmt.Errorf("invalid issue number in %q", ref)
+	}
+	return repourl.Expand(ref[:i]), number, nil
+}
+
+// adoptedIssue returns a GithubIssue bound to an existing GitHub issue. The title, body, labels and assignees are
+// copied from it, since the operator edits the bound issue to match the spec
+func adoptedIssue(namespace string, name string, repoURL string, ghIssue *github.Issue) *issuesv1.GithubIssue {
+	if name == "" {
+		owner, repo, _ := repourl.Parse(repoURL)
+		name = adoptedName(owner, repo, ghIssue.GetNumber())
+	}
+	return &issuesv1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: issuesv1.GithubIssueSpec{
+			Repo:        repoURL,
+			Title:       ghIssue.GetTitle(),
+			Description: ghIssue.GetBody(),
+			Labels:      labelNames(ghIssue.Labels),
+			Assignees:   logins(ghIssue.Assignees),
+			Number:      ghIssue.GetNumber(),
+		},
+	}
+}
+
+func labelNames(labels []*github.Label) []string {
+	var names []string
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names
+}
+
+func logins(users []*github.User) []string {
+	var names []string
+	for _, user := range users {
+		names = append(names, user.GetLogin())
+	}
+	return names
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func adoptedName(owner string, repo string, number int) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(owner+"-"+repo), "-")
+	return fmt.Sprintf("%s-%d", strings.Trim(name, "-"), number)
+}
+
+// boundNumbers returns the numbers of the issues of a repository already bound to a GithubIssue in the namespace
+func boundNumbers(ctx context.Context, c client.Client, namespace string, repoURL string) (map[int]bool, error) {
+	key, err := repourl.Key(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	issues := &issuesv1.GithubIssueList{}
+	if err := c.List(ctx, issues, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	bound := map[int]bool{}
+	for _, issue := range issues.Items {
+		if issueKey, err := repourl.Key(issue.Spec.Repo); err != nil || issueKey != key {
+			continue
+		}
+		bound[issue.Spec.Number] = true
+		bound[issue.Status.Number] = true
+	}
+	return bound, nil
+}
+
+func runClose(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("close", false)
+	reason := fs.String("reason", "completed", "Why the issue is closed, completed or not_planned.")
+	positional, err := parse(fs, args, "<name>")
+	if err != nil {
+		return err
+	}
+	if *reason != "completed" && *reason != "not_planned" {
+		return fmt.Errorf("invalid reason %q, expected completed or not_planned", *reason)
+	}
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	issue := &issuesv1.GithubIssue{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: positional[0]}, issue); err != nil {
+		return err
+	}
+	issue.Spec.State = "closed"
+	issue.Spec.StateReason = *reason
+	if err := c.Update(ctx, issue); err != nil {
+		return err
+	}
+	fmt.Printf("githubissue/%s closed\n", issue.Name)
+	return nil
+}
+
+func runSync(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("sync", false)
+	positional, err := parse(fs, args, "<name>")
+	if err != nil {
+		return err
+	}
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{
+			issuesv1.ReconcileRequestedAnnotation: time.Now().UTC().Format(time.RFC3339Nano),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	issue := &issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: positional[0]}}
+	if err := c.Patch(ctx, issue, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return err
+	}
+	fmt.Printf("githubissue/%s sync requested\n", issue.Name)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Commands", func() {
+	DescribeTable("parsing an issue reference",
+		func(ref string, repoURL string, number int) {
+			gotRepo, gotNumber, err := parseIssueRef(ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gotRepo).To(Equal(repoURL))
+			Expect(gotNumber).To(Equal(number))
+		},
+		Entry("owner/repo", "owner/repo#12", "https://github.com/owner/repo", 12),
+		Entry("a url", "https://ghe.example.com/owner/repo#3", "https://ghe.example.com/owner/repo", 3),
+	)
+
+	DescribeTable("rejecting an issue reference",
+		func(ref string, message string) {
+			_, _, err := parseIssueRef(ref)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("without a number", "owner/repo", "expected <repo>#<number>"),
+		Entry("with a name", "owner/repo#one", "invalid issue number"),
+		Entry("with zero", "owner/repo#0", "invalid issue number"),
+		Entry("with a negative number", "owner/repo#-1", "invalid issue number"),
+	)
+
+	DescribeTable("naming an adopted issue",
+		func(owner string, repo string, number int, name string) {
+			Expect(adoptedName(owner, repo, number)).To(Equal(name))
+		},
+		Entry("a plain repo", "owner", "repo", 1, "owner-repo-1"),
+		Entry("upper case", "Owner", "Repo", 2, "owner-repo-2"),
+		Entry("dots and underscores", "my.org", "my_repo", 3, "my-org-my-repo-3"),
+		Entry("leading and trailing invalid characters", "_owner", "repo.", 4, "owner-repo-4"),
+	)
+
+	DescribeTable("finding the bound issue numbers",
+		func(namespace string, repoURL string, bound map[int]bool) {
+			s := runtime.NewScheme()
+			Expect(issuesv1.AddToScheme(s)).To(Succeed())
+			issue := func(name string, namespace string, repoURL string, specNumber int, statusNumber int) client.Object {
+				return &issuesv1.GithubIssue{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec:       issuesv1.GithubIssueSpec{Repo: repoURL, Title: name, Number: specNumber},
+					Status:     issuesv1.GithubIssueStatus{Number: statusNumber},
+				}
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				issue("adopted", "default", "https://github.com/owner/repo", 1, 1),
+				issue("created", "default", "https://github.com/Owner/Repo.git", 0, 2),
+				issue("other-repo", "default", "https://github.com/owner/other", 0, 3),
+				issue("other-namespace", "ops", "https://github.com/owner/repo", 4, 4),
+			).Build()
+			numbers, err := boundNumbers(context.Background(), c, namespace, repoURL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numbers).To(Equal(bound))
+		},
+		Entry("the issues of the repo in the namespace", "default", "https://github.com/owner/repo", map[int]bool{0: true, 1: true, 2: true}),
+		Entry("another namespace", "ops", "https://github.com/owner/repo", map[int]bool{4: true}),
+		Entry("a repo without issues", "default", "https://github.com/owner/none", map[int]bool{}),
+	)
+})
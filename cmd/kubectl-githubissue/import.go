@@ -0,0 +1,193 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"sigs.k8s.io/yaml"
+)
+
+// importFilters select the issues of a repository to import
+type importFilters struct {
+	state     string
+	labels    string
+	milestone string
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs, kf := newFlagSet("import", false)
+	filters := &importFilters{}
+	fs.StringVar(&filters.state, "state", "open", "Import open, closed or all issues.")
+	fs.StringVar(&filters.labels, "label", "", "Comma separated labels the issues must all have.")
+	fs.StringVar(&filters.milestone, "milestone", "", "Milestone of the issues, a title or number, * for any or none.")
+	output := fs.String("output", "", "Print the GithubIssue manifests instead of creating them, only yaml is supported.")
+	fs.StringVar(output, "o", "", "Shorthand for --output.")
+	outputDir := fs.String("output-dir", "", "Write a GithubIssue manifest per issue to this directory instead of creating them.")
+	positional, err := parse(fs, args, "<repo>")
+	if err != nil {
+		return err
+	}
+	if *output != "" && *output != "yaml" {
+		return fmt.Errorf("unsupported output %q, only yaml is supported", *output)
+	}
+	repoURL := repourl.Expand(positional[0])
+	owner, repo, err := repourl.Parse(repoURL)
+	if err != nil {
+		return err
+	}
+
+	gh := gitHubClient()
+	ghIssues, err := listIssues(ctx, gh, owner, repo, filters)
+	if err != nil {
+		return err
+	}
+	if *output != "" || *outputDir != "" {
+		return writeManifests(kf.namespace, repoURL, ghIssues, *outputDir)
+	}
+
+	c, namespace, err := kf.client()
+	if err != nil {
+		return err
+	}
+	bound, err := boundNumbers(ctx, c, namespace, repoURL)
+	if err != nil {
+		return err
+	}
+	for _, ghIssue := range ghIssues {
+		if bound[ghIssue.GetNumber()] {
+			continue
+		}
+		issue := adoptedIssue(namespace, "", repoURL, ghIssue)
+		if err := c.Create(ctx, issue); err != nil {
+			return err
+		}
+		fmt.Printf("githubissue/%s adopted %s\n", issue.Name, ghIssue.GetHTMLURL())
+	}
+	return nil
+}
+
+// listIssues lists the issues of a repository matching the filters, pull requests are left out
+func listIssues(ctx context.Context, gh *github.Client, owner string, repo string, filters *importFilters) ([]*github.Issue, error) {
+	if filters.state != "open" && filters.state != "closed" && filters.state != "all" {
+		return nil, fmt.Errorf("invalid state %q, expected open, closed or all", filters.state)
+	}
+	opt := &github.IssueListByRepoOptions{State: filters.state, ListOptions: github.ListOptions{PerPage: 100}}
+	if filters.labels != "" {
+		opt.Labels = strings.Split(filters.labels, ",")
+	}
+	milestone, err := milestoneFilter(ctx, gh, owner, repo, filters.milestone)
+	if err != nil {
+		return nil, err
+	}
+	opt.Milestone = milestone
+
+	var issues []*github.Issue
+	for {
+		page, response, err := gh.Issues.ListByRepo(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed listing issues of %s/%s: %v", owner, repo, err.Error())
+		}
+		for _, ghIssue := range page {
+			if !ghIssue.IsPullRequest() {
+				issues = append(issues, ghIssue)
+			}
+		}
+		if response.NextPage == 0 {
+			return issues, nil
+		}
+		opt.Page = response.NextPage
+	}
+}
+
+// milestoneFilter turns a milestone title into the number GitHub filters issues by
+func milestoneFilter(ctx context.Context, gh *github.Client, owner string, repo string, milestone string) (string, error) {
+	if _, err := strconv.Atoi(milestone); err == nil || milestone == "" || milestone == "*" || milestone == "none" {
+		return milestone, nil
+	}
+	opt := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		milestones, response, err := gh.Issues.ListMilestones(ctx, owner, repo, opt)
+		if err != nil {
+			return "", fmt.Errorf("failed listing milestones of %s/%s: %v", owner, repo, err.Error())
+		}
+		for _, m := range milestones {
+			if m.GetTitle() == milestone {
+				return strconv.Itoa(m.GetNumber()), nil
+			}
+		}
+		if response.NextPage == 0 {
+			return "", fmt.Errorf("milestone %q not found in %s/%s", milestone, owner, repo)
+		}
+		opt.Page = response.NextPage
+	}
+}
+
+// writeManifests writes the GithubIssue manifests of the issues to stdout, or a file per issue in dir.
+// The namespace is left out when not set, for tools that set it such as kustomize
+func writeManifests(namespace string, repoURL string, ghIssues []*github.Issue, dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	for i, ghIssue := range ghIssues {
+		issue := adoptedIssue(namespace, "", repoURL, ghIssue)
+		issue.APIVersion = issuesv1.GroupVersion.String()
+		issue.Kind = "GithubIssue"
+		manifest, err := yaml.Marshal(manifestOf(issue))
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			if i > 0 {
+				fmt.Println("---")
+			}
+			fmt.Print(string(manifest))
+			continue
+		}
+		path := filepath.Join(dir, issue.Name+".yaml")
+		if err := os.WriteFile(path, manifest, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+	}
+	return nil
+}
+
+// manifestOf keeps the fields of a GithubIssue that belong in a manifest, leaving out the empty status and
+// creation timestamp
+func manifestOf(issue *issuesv1.GithubIssue) map[string]interface{} {
+	metadata := map[string]interface{}{"name": issue.Name}
+	if issue.Namespace != "" {
+		metadata["namespace"] = issue.Namespace
+	}
+	return map[string]interface{}{
+		"apiVersion": issue.APIVersion,
+		"kind":       issue.Kind,
+		"metadata":   metadata,
+		"spec":       issue.Spec,
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"github.com/google/go-github/v56/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Import", func() {
+	DescribeTable("turning a milestone into its filter",
+		func(milestone string, filter string, message string) {
+			gh := github.NewClient(mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposMilestonesByOwnerByRepo,
+					[]*github.Milestone{
+						{Number: github.Int(1), Title: github.String("v1.0")},
+						{Number: github.Int(2), Title: github.String("v2.0")},
+					},
+				),
+			))
+			got, err := milestoneFilter(context.Background(), gh, "owner", "repo", milestone)
+			if message != "" {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal(filter))
+		},
+		Entry("no milestone", "", "", ""),
+		Entry("any milestone", "*", "*", ""),
+		Entry("issues without a milestone", "none", "none", ""),
+		Entry("a number", "7", "7", ""),
+		Entry("a title", "v2.0", "2", ""),
+		Entry("a missing title", "v3.0", "", `milestone "v3.0" not found in owner/repo`),
+	)
+
+	DescribeTable("keeping the fields of a manifest",
+		func(namespace string, metadata map[string]interface{}) {
+			issue := &issuesv1.GithubIssue{
+				TypeMeta:   metav1.TypeMeta{APIVersion: issuesv1.GroupVersion.String(), Kind: "GithubIssue"},
+				ObjectMeta: metav1.ObjectMeta{Name: "owner-repo-1", Namespace: namespace, CreationTimestamp: metav1.Now()},
+				Spec:       issuesv1.GithubIssueSpec{Repo: "https://github.com/owner/repo", Title: "Disk full", Number: 1},
+				Status:     issuesv1.GithubIssueStatus{Number: 1},
+			}
+			Expect(manifestOf(issue)).To(Equal(map[string]interface{}{
+				"apiVersion": "issues.dvir.io/v1",
+				"kind":       "GithubIssue",
+				"metadata":   metadata,
+				"spec":       issue.Spec,
+			}))
+		},
+		Entry("with a namespace", "ops", map[string]interface{}{"name": "owner-repo-1", "namespace": "ops"}),
+		Entry("without a namespace", "", map[string]interface{}{"name": "owner-repo-1"}),
+	)
+})
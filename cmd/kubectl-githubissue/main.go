@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-githubissue is a kubectl plugin for GithubIssue objects, run as kubectl githubissue <command>
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"github.com/google/go-github/v56/github"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const usage = `Manage GithubIssue objects.
+
+Usage:
+  kubectl githubissue <command> [flags]
+
+Commands:
+  list                      List issues with their GitHub url, state and pull requests
+  open <name>               Open the GitHub issue in the browser
+  adopt <repo>#<number>     Bind an existing GitHub issue to a new GithubIssue
+  import <repo>             Adopt the issues of a repository, or write their manifests with -o yaml
+  close <name>              Close the issue, --reason is completed or not_planned
+  sync <name>               Force a reconcile of the issue
+  plan <dir>                Show the GitHub changes the operator would make for the manifests in a directory
+
+<repo> is a repository url or owner/repo. GitHub is read with the token in GITHUB_TOKEN.
+Run kubectl githubissue <command> -h for the flags of a command.
+`
+
+type command func(ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"list":   runList,
+	"open":   runOpen,
+	"adopt":  runAdopt,
+	"import": runImport,
+	"close":  runClose,
+	"sync":   runSync,
+	"plan":   runPlan,
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := run(context.Background(), os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// kubeFlags are the kubectl flags shared by all commands
+type kubeFlags struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+}
+
+func newFlagSet(name string, withAllNamespaces bool) (*flag.FlagSet, *kubeFlags) {
+	fs := flag.NewFlagSet("kubectl githubissue "+name, flag.ContinueOnError)
+	kf := &kubeFlags{}
+	fs.StringVar(&kf.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&kf.context, "context", "", "The kubeconfig context to use.")
+	fs.StringVar(&kf.namespace, "namespace", "", "The namespace of the issues, defaults to the namespace of the context.")
+	fs.StringVar(&kf.namespace, "n", "", "Shorthand for --namespace.")
+	if withAllNamespaces {
+		fs.BoolVar(&kf.allNamespaces, "all-namespaces", false, "List issues in all namespaces.")
+		fs.BoolVar(&kf.allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	}
+	return fs, kf
+}
+
+// parse parses flags placed before or after the positional arguments, as kubectl does, and checks their count
+func parse(fs *flag.FlagSet, args []string, positional ...string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(rest) != len(positional) {
+		return nil, fmt.Errorf("expected %s", strings.Join(positional, " "))
+	}
+	return rest, nil
+}
+
+// client returns a client for the GithubIssue objects and the namespace to use
+func (kf *kubeFlags) client() (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kf.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kf.context})
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	namespace := kf.namespace
+	if namespace == "" {
+		if namespace, _, err = config.Namespace(); err != nil {
+			return nil, "", err
+		}
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	if err := issuesv1.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	return c, namespace, err
+}
+
+// gitHubClient returns a GitHub client, authenticated when GITHUB_TOKEN is set like the operator
+func gitHubClient() *github.Client {
+	gh := github.NewClient(nil)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		gh = gh.WithAuthToken(token)
+	}
+	return gh
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flag parsing", func() {
+	DescribeTable("parsing flags around the positional arguments",
+		func(args []string, positional []string, rest []string, namespace string, timeout time.Duration) {
+			fs, kf := newFlagSet("test", false)
+			wait := fs.Duration("timeout", 0, "")
+			parsed, err := parse(fs, args, positional...)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(rest))
+			Expect(kf.namespace).To(Equal(namespace))
+			Expect(*wait).To(Equal(timeout))
+		},
+		Entry("no arguments", []string{}, nil, nil, "", time.Duration(0)),
+		Entry("flags before", []string{"-n", "ops", "disk-full"}, []string{"<name>"}, []string{"disk-full"}, "ops", time.Duration(0)),
+		Entry("flags after", []string{"disk-full", "--namespace", "ops"}, []string{"<name>"}, []string{"disk-full"}, "ops", time.Duration(0)),
+		Entry("flags between", []string{"owner/repo#1", "--timeout=1m", "disk-full", "-n=ops"}, []string{"<issue>", "<name>"},
+			[]string{"owner/repo#1", "disk-full"}, "ops", time.Minute),
+	)
+
+	DescribeTable("rejecting arguments",
+		func(args []string, positional []string, message string) {
+			fs, _ := newFlagSet("test", false)
+			fs.SetOutput(GinkgoWriter)
+			_, err := parse(fs, args, positional...)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("a missing argument", []string{"-n", "ops"}, []string{"<name>"}, "expected <name>"),
+		Entry("an extra argument", []string{"a", "b"}, []string{"<name>"}, "expected <name>"),
+		Entry("an unknown flag", []string{"a", "--all-namespaces"}, []string{"<name>"}, "flag provided but not defined"),
+	)
+})
@@ -0,0 +1,211 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	issuesv2 "dvir.io/githubissue/api/v2"
+	"dvir.io/githubissue/internal/plan"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+func runPlan(ctx context.Context, args []string) error {
+	flags, _ := newFlagSet("plan", false)
+	positional, err := parse(flags, args, "<dir>")
+	if err != nil {
+		return err
+	}
+	issues, err := readManifests(positional[0])
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Fprintln(os.Stderr, "No GithubIssue manifests found.")
+		return nil
+	}
+
+	planner := &planner{gh: gitHubClient(), openIssues: map[string][]*github.Issue{}}
+	for _, issue := range issues {
+		p, notes, err := planner.plan(ctx, issue)
+		if err != nil {
+			return fmt.Errorf("githubissue/%s: %v", issue.Name, err.Error())
+		}
+		header := fmt.Sprintf("githubissue/%s: %s", issue.Name, p.Action)
+		if p.Number != 0 {
+			header += fmt.Sprintf(" #%d", p.Number)
+		}
+		fmt.Println(header)
+		for _, line := range p.Diff {
+			fmt.Printf("  %s\n", line)
+		}
+		for _, note := range notes {
+			fmt.Printf("  note: %s\n", note)
+		}
+	}
+	return nil
+}
+
+// readManifests reads the GithubIssue objects of the yaml files in a directory, or of a single file.
+// Other kinds are skipped and v2 objects are converted to v1
+func readManifests(path string) ([]*issuesv1.GithubIssue, error) {
+	var issues []*issuesv1.GithubIssue
+	err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (filepath.Ext(file) != ".yaml" && filepath.Ext(file) != ".yml") {
+			return nil
+		}
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+		for {
+			obj := &unstructured.Unstructured{}
+			if err := decoder.Decode(&obj.Object); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return fmt.Errorf("%s: %v", file, err.Error())
+			}
+			if obj.GetKind() != "GithubIssue" {
+				continue
+			}
+			issue, err := toV1(obj)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file, err.Error())
+			}
+			issues = append(issues, issue)
+		}
+	})
+	return issues, err
+}
+
+func toV1(obj *unstructured.Unstructured) (*issuesv1.GithubIssue, error) {
+	raw, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	issue := &issuesv1.GithubIssue{}
+	switch obj.GetAPIVersion() {
+	case issuesv1.GroupVersion.String():
+		err = yaml.UnmarshalStrict(raw, issue)
+	case issuesv2.GroupVersion.String():
+		hub := &issuesv2.GithubIssue{}
+		if err = yaml.UnmarshalStrict(raw, hub); err == nil {
+			err = issue.ConvertFrom(hub)
+		}
+	default:
+		err = fmt.Errorf("unsupported apiVersion %s", obj.GetAPIVersion())
+	}
+	return issue, err
+}
+
+// planner plans the manifests against GitHub the way the reconciler does, caching the open issues of each repository
+type planner struct {
+	gh         *github.Client
+	openIssues map[string][]*github.Issue
+}
+
+// plan computes the plan of a manifest. Descriptions read from the cluster and templates cannot be resolved offline,
+// they are planned as unchanged and reported in the notes
+func (p *planner) plan(ctx context.Context, issue *issuesv1.GithubIssue) (plan.Plan, []string, error) {
+	owner, repo, err := repourl.Parse(issue.Spec.Repo)
+	if err != nil {
+		return plan.Plan{}, nil, err
+	}
+	current, err := p.find(ctx, owner, repo, issue)
+	if err != nil {
+		return plan.Plan{}, nil, err
+	}
+
+	desired := plan.Issue{
+		Title:       issue.Spec.Title,
+		Body:        issue.Spec.Description,
+		Labels:      issue.Spec.Labels,
+		Assignees:   issue.Spec.Assignees,
+		State:       issue.Spec.State,
+		StateReason: issue.Spec.StateReason,
+	}
+	var notes []string
+	if len(issue.Spec.TemplateInputs) > 0 {
+		notes = append(notes, "the title and description are templates rendered in the cluster, they are not planned")
+		if current != nil {
+			desired.Title = current.GetTitle()
+			desired.Body = current.GetBody()
+		}
+	}
+	if issue.Spec.DescriptionFrom != nil {
+		notes = append(notes, "the description is read from the cluster, it is not planned")
+		if current != nil {
+			desired.Body = current.GetBody()
+		}
+	}
+	if issue.Spec.MilestoneRef != nil {
+		notes = append(notes, fmt.Sprintf("the milestone of GithubMilestone %s is not planned", issue.Spec.MilestoneRef.Name))
+	}
+	return plan.Compute(current, desired), notes, nil
+}
+
+// find returns the issue bound by number, or the open issue with the same title like the reconciler
+func (p *planner) find(ctx context.Context, owner string, repo string, issue *issuesv1.GithubIssue) (*github.Issue, error) {
+	if issue.Spec.Number != 0 {
+		current, _, err := p.gh.Issues.Get(ctx, owner, repo, issue.Spec.Number)
+		if err != nil {
+			return nil, fmt.Errorf("failed fetching issue #%d: %v", issue.Spec.Number, err.Error())
+		}
+		return current, nil
+	}
+	key := owner + "/" + repo
+	if _, ok := p.openIssues[key]; !ok {
+		var all []*github.Issue
+		opt := &github.IssueListByRepoOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			page, response, err := p.gh.Issues.ListByRepo(ctx, owner, repo, opt)
+			if err != nil {
+				return nil, fmt.Errorf("failed listing issues of %s: %v", key, err.Error())
+			}
+			all = append(all, page...)
+			if response.NextPage == 0 {
+				break
+			}
+			opt.Page = response.NextPage
+		}
+		p.openIssues[key] = all
+	}
+	for _, current := range p.openIssues[key] {
+		if strings.EqualFold(current.GetTitle(), issue.Spec.Title) {
+			return current, nil
+		}
+	}
+	return nil, nil
+}
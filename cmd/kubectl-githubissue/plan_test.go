@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan", func() {
+	DescribeTable("reading manifests",
+		func(manifest string, titles []string, message string) {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "issues.yaml"), []byte(manifest), 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o644)).To(Succeed())
+			issues, err := readManifests(dir)
+			if message != "" {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			var got []string
+			for _, issue := range issues {
+				Expect(issue.Spec.Repo).To(Equal("https://github.com/owner/repo"))
+				got = append(got, issue.Spec.Title)
+			}
+			Expect(got).To(Equal(titles))
+		},
+		Entry("a v1 issue", `
+apiVersion: issues.dvir.io/v1
+kind: GithubIssue
+metadata:
+  name: disk-full
+spec:
+  repo: https://github.com/owner/repo
+  title: Disk full
+`, []string{"Disk full"}, ""),
+		Entry("a v2 issue, converted to v1", `
+apiVersion: issues.dvir.io/v2
+kind: GithubIssue
+metadata:
+  name: disk-full
+spec:
+  repo:
+    owner: owner
+    name: repo
+  title: Disk full
+`, []string{"Disk full"}, ""),
+		Entry("other kinds along with issues", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: issues.dvir.io/v1
+kind: GithubIssue
+metadata:
+  name: disk-full
+spec:
+  repo: https://github.com/owner/repo
+  title: Disk full
+`, []string{"Disk full"}, ""),
+		Entry("an unknown field", `
+apiVersion: issues.dvir.io/v1
+kind: GithubIssue
+metadata:
+  name: disk-full
+spec:
+  repo: https://github.com/owner/repo
+  title: Disk full
+  priority: high
+`, nil, "issues.yaml"),
+		Entry("an unsupported version", `
+apiVersion: issues.dvir.io/v3
+kind: GithubIssue
+metadata:
+  name: disk-full
+`, nil, "unsupported apiVersion issues.dvir.io/v3"),
+	)
+
+	It("reads a single file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "issue.yml")
+		manifest := "apiVersion: issues.dvir.io/v1\nkind: GithubIssue\nmetadata:\n  name: a\nspec:\n  repo: https://github.com/owner/repo\n  title: A\n"
+		Expect(os.WriteFile(path, []byte(manifest), 0o644)).To(Succeed())
+		issues, err := readManifests(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].Name).To(Equal("a"))
+	})
+})
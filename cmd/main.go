@@ -18,12 +18,13 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v56/github"
 	"go.elastic.co/ecszap"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -31,18 +32,34 @@ import (
 
 	uberzap "go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	issuesv1 "dvir.io/githubissue/api/v1"
+	issuesv2 "dvir.io/githubissue/api/v2"
+	"dvir.io/githubissue/internal/alertmanager"
 	"dvir.io/githubissue/internal/controller"
+	"dvir.io/githubissue/internal/graphql"
+	"dvir.io/githubissue/internal/quota"
+	"dvir.io/githubissue/internal/repourl"
+	"dvir.io/githubissue/internal/safety"
+	"dvir.io/githubissue/internal/trigger"
+	webhookv1 "dvir.io/githubissue/internal/webhook/v1"
+	webhookv2 "dvir.io/githubissue/internal/webhook/v2"
 	//+kubebuilder:scaffold:imports
 )
 
+// quotaConfigMap is the ConfigMap the create quotas are counted in
+const quotaConfigMap = "githubissue-create-quota"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -52,6 +69,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(issuesv1.AddToScheme(scheme))
+	utilruntime.Must(issuesv2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -59,44 +77,322 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var webhookCertDir string
+	var verifyRepoAccess bool
+	var clusterName string
+	var dryRun bool
+	var alertmanagerAddr string
+	var reportWorkloadFailures bool
+	var workloadLogLines int64
+	var reconcileAddr string
+	var reconcileCertDir string
+	var reconcileInsecure bool
+	var syncInterval time.Duration
+	var minSyncInterval time.Duration
+	var maxConcurrentReconciles int
+	var graphqlBatching bool
+	var graphqlBatchTTL time.Duration
+	var contentSafety string
+	var contentSafetyRules string
+	var contentEntropyThreshold float64
+	var quotaNamespace string
+	limiter := &quota.Limiter{}
+	shard := controller.Shard{}
+	receiver := &alertmanager.Receiver{}
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"The directory containing tls.crt and tls.key for the webhook server. "+
+			"Defaults to the cert-manager mounted directory.")
+	flag.BoolVar(&verifyRepoAccess, "webhook-verify-repo", false,
+		"Reject GithubIssue objects whose repo does not exist or cannot be written to with the GitHub token.")
+	flag.StringVar(&clusterName, "cluster-name", "",
+		"The name of this cluster, available to the body footer templates of GithubIssueDefaults.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Plan the GitHub mutations of every GithubIssue, GithubIssueComment, GithubLabelSet and GithubMilestone into their status and Events without making them.")
+	flag.BoolVar(&reportWorkloadFailures, "report-workload-failures", false,
+		"File issues for failing Pods, Jobs and Deployments annotated with "+issuesv1.ReportFailuresAnnotation+".")
+	flag.Int64Var(&workloadLogLines, "workload-log-lines", 50,
+		"The number of log lines of each failing container included in workload failure issues.")
+	flag.StringVar(&alertmanagerAddr, "alertmanager-bind-address", "0",
+		"The address the Alertmanager webhook receiver binds to. Set this to '0' to disable it. Requires the ALERTMANAGER_TOKEN environment variable.")
+	flag.StringVar(&receiver.Namespace, "alertmanager-namespace", "default",
+		"The namespace the GithubIssue objects of alerts are created in.")
+	flag.StringVar(&receiver.Repo, "alertmanager-repo", "",
+		"The repository alerts are filed in, unless they carry a github_repo label or annotation.")
+	flag.StringVar(&receiver.TitleTemplate, "alertmanager-title-template", alertmanager.DefaultTitle,
+		"The template of the issue title, rendered with the alert labels and annotations.")
+	flag.StringVar(&receiver.BodyTemplate, "alertmanager-body-template", alertmanager.DefaultBody,
+		"The template of the issue body, rendered with the alert labels and annotations.")
+	flag.StringVar(&receiver.ResolveAction, "alertmanager-resolve-action", alertmanager.ResolveClose,
+		"What to do with the issue of a resolved alert, 'close' it or 'delete' the GithubIssue.")
+	flag.StringVar(&receiver.KeyBy, "alertmanager-key", alertmanager.KeyFingerprint,
+		"File an issue per alert 'fingerprint' or per alert 'group'.")
+	flag.StringVar(&reconcileAddr, "reconcile-bind-address", "0",
+		"The address the on demand reconcile endpoint binds to. Set this to '0' to disable it.")
+	flag.StringVar(&reconcileCertDir, "reconcile-cert-dir", "",
+		"The directory containing tls.crt and tls.key for the reconcile endpoint, it does not start without it unless --trigger-insecure is set.")
+	flag.BoolVar(&reconcileInsecure, "trigger-insecure", false,
+		"Serve the reconcile endpoint over plain http when --reconcile-cert-dir is empty. Callers send ServiceAccount tokens, only use it behind a TLS terminating proxy or for development.")
+	flag.DurationVar(&syncInterval, "sync-interval", time.Minute,
+		"How often GithubIssue objects without spec.syncInterval are refreshed from GitHub.")
+	flag.DurationVar(&minSyncInterval, "min-sync-interval", 30*time.Second,
+		"The shortest spec.syncInterval honored, shorter intervals are raised to it.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 4,
+		"The number of GithubIssue objects reconciled concurrently. Objects of the same repository are reconciled one at a time.")
+	flag.IntVar(&shard.Count, "shards", 1,
+		"The number of managers the GithubIssue objects are spread across, by a hash of their repository.")
+	flag.IntVar(&shard.Index, "shard-index", 0,
+		"The shard of this manager, from 0 to --shards - 1. Shard 0 also runs every other controller.")
+	flag.StringVar(&shard.Label, "shard-label", "",
+		"A label whose value is hashed instead of the repository of the GithubIssue objects that have it. "+
+			"Issues of a repository split across shards by it are reconciled concurrently, which gives up the per repository serialization.")
+	flag.BoolVar(&graphqlBatching, "graphql-batching", false,
+		"Refresh the issues of a repository together with a GraphQL query instead of a REST call per issue.")
+	flag.DurationVar(&graphqlBatchTTL, "graphql-batch-ttl", 10*time.Second,
+		"How long issues fetched along with another issue of their repository are served to their own reconcile.")
+	flag.StringVar(&contentSafety, "content-safety", safety.ActionRedact,
+		"What to do with titles, bodies and comments that look like they contain credentials: off, redact or block.")
+	flag.StringVar(&contentSafetyRules, "content-safety-rules", "",
+		"A YAML file of extra safety rules, a list of name and pattern, checked along with the built-in rules.")
+	flag.Float64Var(&contentEntropyThreshold, "content-entropy-threshold", safety.DefaultEntropyThreshold,
+		"The Shannon entropy in bits per character above which long tokens are treated as secrets, 0 disables it.")
+	flag.IntVar(&limiter.PerNamespace, "create-quota-per-namespace", 0,
+		"The number of issues each namespace may create in a --create-quota-window, 0 means no limit.")
+	flag.IntVar(&limiter.PerRepo, "create-quota-per-repo", 0,
+		"The number of issues created in each repository in a --create-quota-window, 0 means no limit.")
+	flag.IntVar(&limiter.BreakerThreshold, "create-circuit-breaker", 0,
+		"The number of issues created in a --create-quota-window that stops every create until the window expires "+
+			"or the breaker is reset with POST /quota/reset on the reconcile endpoint, 0 disables it.")
+	flag.DurationVar(&limiter.Window, "create-quota-window", quota.DefaultWindow,
+		"The sliding window creates are counted over.")
+	flag.StringVar(&quotaNamespace, "create-quota-namespace", "",
+		"The namespace of the "+quotaConfigMap+" ConfigMap the creates are counted in, shared by every shard. "+
+			"Defaults to the namespace the manager runs in, where the leader election role lets it write ConfigMaps.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
-	println(os.Getenv("GITHUB_TOKEN"))
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 	encoderConfig := ecszap.NewDefaultEncoderConfig()
-	resyncPeriod := 1 * time.Minute
 	core := ecszap.NewCore(encoderConfig, os.Stdout, uberzap.DebugLevel)
 	ctrlog := uberzap.New(core, uberzap.AddCaller())
+	if shard.Count < 1 || shard.Index < 0 || shard.Index >= shard.Count {
+		setupLog.Error(nil, "invalid --shard-index, expected 0 to --shards - 1")
+		os.Exit(1)
+	}
+	// Every shard elects its own leader
+	leaderElectionID := "995e4d87.dvir.io"
+	if shard.Count > 1 {
+		leaderElectionID = fmt.Sprintf("shard-%d.%s", shard.Index, leaderElectionID)
+	}
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "995e4d87.dvir.io",
-		Cache:                  cache.Options{SyncPeriod: &resyncPeriod},
+		LeaderElectionID:       leaderElectionID,
+		WebhookServer:          webhook.NewServer(webhook.Options{CertDir: webhookCertDir}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	gitHubClient := github.NewClient(nil).WithAuthToken(os.Getenv("GITHUB_TOKEN"))
+	var batcher *graphql.Batcher
+	if graphqlBatching {
+		batcher = &graphql.Batcher{Client: graphql.NewClient(gitHubClient), TTL: graphqlBatchTTL}
+	}
+	var scanner *safety.Scanner
+	if contentSafety != "off" {
+		rules := safety.DefaultRules
+		if contentSafetyRules != "" {
+			extra, err := safety.LoadRules(contentSafetyRules)
+			if err != nil {
+				setupLog.Error(err, "unable to load content safety rules")
+				os.Exit(1)
+			}
+			rules = append(append([]safety.Rule{}, rules...), extra...)
+		}
+		scanner, err = safety.NewScanner(contentSafety, rules, contentEntropyThreshold)
+		if err != nil {
+			setupLog.Error(err, "invalid content safety configuration")
+			os.Exit(1)
+		}
+	}
+	if limiter.PerNamespace <= 0 && limiter.PerRepo <= 0 && limiter.BreakerThreshold <= 0 {
+		limiter = nil
+	} else {
+		if quotaNamespace == "" {
+			namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+			if err != nil {
+				setupLog.Error(err, "unable to find the namespace of the manager, set --create-quota-namespace")
+				os.Exit(1)
+			}
+			quotaNamespace = strings.TrimSpace(string(namespace))
+		}
+		// Counts are read and written directly, a cached client would watch every ConfigMap of the cluster
+		limiter.Client, err = client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+		if err != nil {
+			setupLog.Error(err, "unable to create quota client")
+			os.Exit(1)
+		}
+		limiter.ConfigMap = types.NamespacedName{Namespace: quotaNamespace, Name: quotaConfigMap}
+	}
 	if err = (&controller.GithubIssueReconciler{
-		Client:       mgr.GetClient(),
-		Scheme:       mgr.GetScheme(),
-		GitHubClient: github.NewClient(nil).WithAuthToken(os.Getenv("GITHUB_TOKEN")),
-		Log:          ctrlog,
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		GitHubClient:            gitHubClient,
+		Log:                     ctrlog,
+		Recorder:                mgr.GetEventRecorderFor("githubissue-controller"),
+		DryRun:                  dryRun,
+		SyncInterval:            syncInterval,
+		MinSyncInterval:         minSyncInterval,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Shard:                   shard,
+		Batcher:                 batcher,
+		Safety:                  scanner,
+		Quota:                   limiter,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GithubIssue")
 		os.Exit(1)
 	}
+	// The other controllers are not sharded, they only run in the manager of shard 0
+	if shard.Index == 0 {
+		if err = (&controller.GithubIssueCommentReconciler{
+			Client:       mgr.GetClient(),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: gitHubClient,
+			Log:          ctrlog,
+			Safety:       scanner,
+			DryRun:       dryRun,
+			Recorder:     mgr.GetEventRecorderFor("githubissuecomment-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GithubIssueComment")
+			os.Exit(1)
+		}
+		if err = (&controller.GithubLabelSetReconciler{
+			Client:       mgr.GetClient(),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: gitHubClient,
+			Log:          ctrlog,
+			DryRun:       dryRun,
+			Recorder:     mgr.GetEventRecorderFor("githublabelset-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GithubLabelSet")
+			os.Exit(1)
+		}
+		if err = (&controller.GithubMilestoneReconciler{
+			Client:       mgr.GetClient(),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: gitHubClient,
+			Log:          ctrlog,
+			DryRun:       dryRun,
+			Recorder:     mgr.GetEventRecorderFor("githubmilestone-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GithubMilestone")
+			os.Exit(1)
+		}
+		// Children of sets are defaulted the way the webhook defaults them, or every reconcile would undo the defaults
+		var issueDefaulter webhook.CustomDefaulter
+		if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+			issueDefaulter = &webhookv1.GithubIssueCustomDefaulter{Client: mgr.GetClient(), ClusterName: clusterName}
+		}
+		if err = (&controller.GithubIssueSetReconciler{
+			Client:       mgr.GetClient(),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: gitHubClient,
+			Log:          ctrlog,
+			Defaulter:    issueDefaulter,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GithubIssueSet")
+			os.Exit(1)
+		}
+		if err = (&controller.GithubIssueScheduleReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Log:    ctrlog,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GithubIssueSchedule")
+			os.Exit(1)
+		}
+		if reportWorkloadFailures {
+			if err = (&controller.WorkloadReconciler{
+				Client:    mgr.GetClient(),
+				Scheme:    mgr.GetScheme(),
+				Clientset: kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+				Log:       ctrlog,
+				LogLines:  workloadLogLines,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "Workload")
+				os.Exit(1)
+			}
+		}
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		var repoVerifier *github.Client
+		if verifyRepoAccess {
+			repoVerifier = gitHubClient
+		}
+		if err = webhookv1.SetupGithubIssueWebhookWithManager(mgr, repoVerifier, clusterName); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubIssue")
+			os.Exit(1)
+		}
+		if err = webhookv2.SetupGithubIssueWebhookWithManager(mgr, repourl.WebHost(gitHubClient.BaseURL)); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubIssue")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupGithubIssueCommentWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubIssueComment")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupGithubLabelSetWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubLabelSet")
+			os.Exit(1)
+		}
+		if err = webhookv1.SetupGithubMilestoneWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubMilestone")
+			os.Exit(1)
+		}
+	}
+	if alertmanagerAddr != "0" {
+		if receiver.ResolveAction != alertmanager.ResolveClose && receiver.ResolveAction != alertmanager.ResolveDelete {
+			setupLog.Error(nil, "invalid --alertmanager-resolve-action, expected close or delete")
+			os.Exit(1)
+		}
+		if receiver.KeyBy != alertmanager.KeyFingerprint && receiver.KeyBy != alertmanager.KeyGroup {
+			setupLog.Error(nil, "invalid --alertmanager-key, expected fingerprint or group")
+			os.Exit(1)
+		}
+		receiver.Client = mgr.GetClient()
+		receiver.Log = ctrlog
+		receiver.Token = os.Getenv("ALERTMANAGER_TOKEN")
+		if receiver.Token == "" {
+			// Alerts can name any repo in their github_repo label, the receiver must not be open to every caller
+			setupLog.Error(nil, "ALERTMANAGER_TOKEN must be set when the alertmanager receiver is enabled")
+			os.Exit(1)
+		}
+		if err = mgr.Add(&alertmanager.Server{Addr: alertmanagerAddr, Receiver: receiver}); err != nil {
+			setupLog.Error(err, "unable to add alertmanager receiver")
+			os.Exit(1)
+		}
+	}
+	if reconcileAddr != "0" {
+		handler := &trigger.Handler{Client: mgr.GetClient(), Log: ctrlog}
+		server := &trigger.Server{Addr: reconcileAddr, CertDir: reconcileCertDir, Insecure: reconcileInsecure, Handler: handler}
+		if limiter != nil {
+			server.Quota = &trigger.QuotaHandler{Client: mgr.GetClient(), Log: ctrlog, Limiter: limiter, Elected: mgr.Elected()}
+		}
+		if err = mgr.Add(server); err != nil {
+			setupLog.Error(err, "unable to add reconcile endpoint")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,274 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package alertmanager receives Alertmanager webhook notifications and files them as GithubIssue objects
+package alertmanager
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/render"
+	"dvir.io/githubissue/internal/repourl"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ResolveDelete deletes the GithubIssue of a resolved alert, its finalizer closes the issue
+	ResolveDelete = "delete"
+
+	// ResolveClose sets the GithubIssue of a resolved alert to closed, keeping it for history
+	ResolveClose = "close"
+
+	// KeyFingerprint files an issue per alert
+	KeyFingerprint = "fingerprint"
+
+	// KeyGroup files an issue per alert group
+	KeyGroup = "group"
+)
+
+// RepoLabel is the alert label or annotation overriding the repository an alert is filed in, either a repository url or owner/repo
+const RepoLabel = "github_repo"
+
+// DefaultTitle is the title template used when none is configured
+const DefaultTitle = `{{ index .Labels "alertname" }}{{ with index .Annotations "summary" }}: {{ . }}{{ end }}`
+
+// DefaultBody is the body template used when none is configured
+const DefaultBody = `{{ with index .Annotations "description" }}{{ . }}
+
+{{ end }}| Label | Value |
+| --- | --- |
+{{ range $name, $value := .Labels }}| {{ $name }} | {{ $value }} |
+{{ end }}{{ with .GeneratorURL }}
+[Source]({{ . }}){{ end }}`
+
+// Message is the payload of an Alertmanager webhook notification
+type Message struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert is an alert of an Alertmanager webhook notification
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// templateData is exposed to the title and body templates. Labels and annotations are those of the alert,
+// or the common ones of the group when issues are keyed by group
+type templateData struct {
+	Status       string
+	Labels       map[string]string
+	Annotations  map[string]string
+	StartsAt     time.Time
+	GeneratorURL string
+	Fingerprint  string
+	GroupLabels  map[string]string
+	ExternalURL  string
+	Alerts       []Alert
+}
+
+// Receiver creates, updates and resolves GithubIssue objects from Alertmanager notifications
+type Receiver struct {
+	Client    client.Client
+	Log       *zap.Logger
+	Namespace string
+	//Repo alerts are filed in, unless they carry a github_repo label or annotation
+	Repo string
+	//Token expected as a bearer token, every request is rejected when empty
+	Token         string
+	TitleTemplate string
+	BodyTemplate  string
+	//Labels set on every issue
+	Labels []string
+	//ResolveAction is ResolveDelete or ResolveClose
+	ResolveAction string
+	//KeyBy is KeyFingerprint or KeyGroup
+	KeyBy string
+}
+
+// ServeHTTP handles an Alertmanager webhook notification
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || r.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	message := &Message{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(message); err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload: %v", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err := r.Handle(req.Context(), message); err != nil {
+		r.Log.Error("failed handling alertmanager notification", zap.Error(err))
+		// Alertmanager retries notifications answered with a 5xx
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handle files the alerts of a notification
+func (r *Receiver) Handle(ctx context.Context, message *Message) error {
+	if r.KeyBy == KeyGroup {
+		data := templateData{
+			Status:      message.Status,
+			Labels:      message.CommonLabels,
+			Annotations: message.CommonAnnotations,
+			GroupLabels: message.GroupLabels,
+			ExternalURL: message.ExternalURL,
+			Alerts:      message.Alerts,
+		}
+		if len(message.Alerts) > 0 {
+			data.StartsAt = message.Alerts[0].StartsAt
+			data.GeneratorURL = message.Alerts[0].GeneratorURL
+		}
+		return r.handle(ctx, "alert-"+hash(message.GroupKey), data)
+	}
+	var errs []error
+	for _, alert := range message.Alerts {
+		if alert.Fingerprint == "" {
+			errs = append(errs, fmt.Errorf("alert %s has no fingerprint", alert.Labels["alertname"]))
+			continue
+		}
+		data := templateData{
+			Status:       alert.Status,
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			StartsAt:     alert.StartsAt,
+			GeneratorURL: alert.GeneratorURL,
+			Fingerprint:  alert.Fingerprint,
+			GroupLabels:  message.GroupLabels,
+			ExternalURL:  message.ExternalURL,
+			Alerts:       []Alert{alert},
+		}
+		if err := r.handle(ctx, "alert-"+strings.ToLower(alert.Fingerprint), data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Receiver) handle(ctx context.Context, name string, data templateData) error {
+	issue := &issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.Namespace}}
+	if data.Status == "resolved" {
+		return r.resolve(ctx, issue)
+	}
+
+	repo := r.Repo
+	if value, ok := data.Annotations[RepoLabel]; ok {
+		repo = value
+	}
+	if value, ok := data.Labels[RepoLabel]; ok {
+		repo = value
+	}
+	if repo == "" {
+		return fmt.Errorf("no repository for %s, set a default repository or the %s label", name, RepoLabel)
+	}
+	repo = repourl.Expand(repo)
+	title, err := render.Render("title", orDefault(r.TitleTemplate, DefaultTitle), data)
+	if err != nil {
+		return err
+	}
+	body, err := render.Render("body", orDefault(r.BodyTemplate, DefaultBody), data)
+	if err != nil {
+		return err
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, issue, func() error {
+		if issue.Labels == nil {
+			issue.Labels = map[string]string{}
+		}
+		issue.Labels[issuesv1.SourceLabel] = "alertmanager"
+		issue.Spec.Repo = repo
+		issue.Spec.Title = title
+		issue.Spec.Description = body
+		issue.Spec.Labels = r.Labels
+		//Reopen the issue of an alert firing again
+		issue.Spec.State = "open"
+		issue.Spec.StateReason = ""
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed filing %s: %v", name, err.Error())
+	}
+	r.Log.Info(fmt.Sprintf("firing alert filed as %s: %s", name, result))
+	return nil
+}
+
+func (r *Receiver) resolve(ctx context.Context, issue *issuesv1.GithubIssue) error {
+	if r.ResolveAction == ResolveClose {
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(issue), issue); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if issue.Spec.State == "closed" {
+			return nil
+		}
+		issue.Spec.State = "closed"
+		issue.Spec.StateReason = "completed"
+		if err := r.Client.Update(ctx, issue); err != nil {
+			return fmt.Errorf("failed closing %s: %v", issue.Name, err.Error())
+		}
+		r.Log.Info(fmt.Sprintf("resolved alert closed %s", issue.Name))
+		return nil
+	}
+	if err := r.Client.Delete(ctx, issue); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed deleting %s: %v", issue.Name, err.Error())
+	}
+	r.Log.Info(fmt.Sprintf("resolved alert deleted %s", issue.Name))
+	return nil
+}
+
+func hash(s string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
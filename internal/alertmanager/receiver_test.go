@@ -0,0 +1,118 @@
+package alertmanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const notification = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighLatency\"}",
+  "status": "%s",
+  "groupLabels": {"alertname": "HighLatency"},
+  "commonLabels": {"alertname": "HighLatency", "severity": "page"},
+  "commonAnnotations": {"summary": "latency is high"},
+  "alerts": [{
+    "status": "%s",
+    "labels": {"alertname": "HighLatency", "severity": "page", "github_repo": "test/test"},
+    "annotations": {"summary": "latency is high", "description": "p99 is above 1s"},
+    "fingerprint": "ABC123"
+  }]
+}`
+
+var _ = Describe("Alertmanager receiver", func() {
+	var (
+		k8sClient client.Client
+		receiver  *Receiver
+		ctx       = context.Background()
+	)
+
+	post := func(status string, token string) int {
+		body := strings.NewReplacer("%s", status).Replace(notification)
+		req := httptest.NewRequest(http.MethodPost, "/alerts", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		receiver.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	BeforeEach(func() {
+		s := runtime.NewScheme()
+		Expect(issuesv1.AddToScheme(s)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(s).Build()
+		receiver = &Receiver{
+			Client:        k8sClient,
+			Log:           zap.NewNop(),
+			Namespace:     "default",
+			ResolveAction: ResolveClose,
+			KeyBy:         KeyFingerprint,
+			Token:         "secret",
+		}
+	})
+
+	It("files a firing alert and closes it when resolved", func() {
+		Expect(post("firing", "secret")).To(Equal(http.StatusOK))
+		issue := &issuesv1.GithubIssue{}
+		key := types.NamespacedName{Name: "alert-abc123", Namespace: "default"}
+		Expect(k8sClient.Get(ctx, key, issue)).To(Succeed())
+		Expect(issue.Spec.Repo).To(Equal("https://github.com/test/test"))
+		Expect(issue.Spec.Title).To(Equal("HighLatency: latency is high"))
+		Expect(issue.Spec.Description).To(ContainSubstring("p99 is above 1s"))
+		Expect(issue.Spec.State).To(Equal("open"))
+		Expect(issue.Labels).To(HaveKeyWithValue(issuesv1.SourceLabel, "alertmanager"))
+
+		Expect(post("resolved", "secret")).To(Equal(http.StatusOK))
+		Expect(k8sClient.Get(ctx, key, issue)).To(Succeed())
+		Expect(issue.Spec.State).To(Equal("closed"))
+		Expect(issue.Spec.StateReason).To(Equal("completed"))
+
+		Expect(post("firing", "secret")).To(Equal(http.StatusOK))
+		Expect(k8sClient.Get(ctx, key, issue)).To(Succeed())
+		Expect(issue.Spec.State).To(Equal("open"))
+	})
+
+	It("deletes the issue of a resolved alert grouped by group key", func() {
+		receiver.ResolveAction = ResolveDelete
+		receiver.KeyBy = KeyGroup
+		receiver.Repo = "https://github.com/test/alerts"
+		Expect(post("firing", "secret")).To(Equal(http.StatusOK))
+		issues := &issuesv1.GithubIssueList{}
+		Expect(k8sClient.List(ctx, issues)).To(Succeed())
+		Expect(issues.Items).To(HaveLen(1))
+		Expect(issues.Items[0].Spec.Repo).To(Equal("https://github.com/test/alerts"))
+
+		Expect(post("resolved", "secret")).To(Equal(http.StatusOK))
+		Expect(k8sClient.List(ctx, issues)).To(Succeed())
+		Expect(issues.Items).To(BeEmpty())
+	})
+
+	It("ignores resolved alerts that were never filed", func() {
+		Expect(post("resolved", "secret")).To(Equal(http.StatusOK))
+		issues := &issuesv1.GithubIssueList{}
+		Expect(k8sClient.List(ctx, issues)).To(Succeed())
+		Expect(issues.Items).To(BeEmpty())
+	})
+
+	It("rejects requests without the token", func() {
+		Expect(post("firing", "")).To(Equal(http.StatusUnauthorized))
+		Expect(post("firing", "wrong")).To(Equal(http.StatusUnauthorized))
+		Expect(post("firing", "secret")).To(Equal(http.StatusOK))
+
+		By("rejecting every request when no token is configured")
+		receiver.Token = ""
+		Expect(post("firing", "")).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const repoIndexKey = "spec.repo"
+
+// indexRepo indexes GithubIssue CRDs by their normalized owner/repo
+func indexRepo(obj client.Object) []string {
+	issue := obj.(*issuesv1.GithubIssue)
+	key, err := repourl.Key(issue.Spec.Repo)
+	if err != nil {
+		return nil
+	}
+	return []string{key}
+}
+
+// fetchBatched gets a bound issue through the GraphQL batcher, along with the other issues of its repository.
+// It returns nil when batching is off or fails, for the caller to fall back to the REST API
+func (r *GithubIssueReconciler) fetchBatched(ctx context.Context, owner string, repo string, issueObject *issuesv1.GithubIssue, number int) *github.Issue {
+	if r.Batcher == nil {
+		return nil
+	}
+	batched, err := r.Batcher.Get(ctx, owner, repo, number, r.pendingNumbers(ctx, issueObject, number))
+	if err != nil {
+		r.Log.Error("failed fetching batched issues, falling back to REST", zap.Error(err))
+		return nil
+	}
+	if batched == nil {
+		return nil
+	}
+	return batched.REST()
+}
+
+// pendingNumbers returns the issue numbers of the other GithubIssue CRDs of the repository reconciled by this manager
+func (r *GithubIssueReconciler) pendingNumbers(ctx context.Context, issueObject *issuesv1.GithubIssue, number int) []int {
+	keys := indexRepo(issueObject)
+	if len(keys) == 0 {
+		return nil
+	}
+	issues := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, issues, client.MatchingFields{repoIndexKey: keys[0]}); err != nil {
+		r.Log.Error("failed listing issues of the repository", zap.Error(err))
+		return nil
+	}
+	var numbers []int
+	for i := range issues.Items {
+		peer := &issues.Items[i]
+		if peerNumber := boundIssueNumber(peer); peerNumber != 0 && peerNumber != number && peer.DeletionTimestamp.IsZero() && r.Shard.Owns(peer) {
+			numbers = append(numbers, peerNumber)
+		}
+	}
+	return numbers
+}
+
+// batchedPRs returns the pull requests linked to an issue fetched by the GraphQL batcher in this reconcile
+func (r *GithubIssueReconciler) batchedPRs(owner string, repo string, number int, known map[string]issuesv1.LinkedPullRequest) ([]issuesv1.LinkedPullRequest, bool) {
+	if r.Batcher == nil {
+		return nil, false
+	}
+	batched := r.Batcher.Served(owner, repo, number)
+	if batched == nil {
+		return nil, false
+	}
+	var linked []issuesv1.LinkedPullRequest
+	for _, pr := range batched.LinkedPRs() {
+		if known[pr.URL].State == "merged" {
+			linked = append(linked, known[pr.URL])
+			continue
+		}
+		linkedPR := issuesv1.LinkedPullRequest{Number: pr.Number, URL: pr.URL, State: strings.ToLower(pr.State),
+			Closes: pr.Closes && sameRepo(pr.URL, owner, repo)}
+		if pr.MergedAt != nil {
+			mergedAt := v1.NewTime(pr.MergedAt.Truncate(time.Second))
+			linkedPR.MergedAt = &mergedAt
+		}
+		linked = append(linked, linkedPR)
+	}
+	sort.Slice(linked, func(i, j int) bool { return linked[i].URL < linked[j].URL })
+	return linked, true
+}
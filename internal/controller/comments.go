@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxCommentBodyLength is the number of characters of a comment body kept in the status
+const maxCommentBodyLength = 280
+
+// RecordActivity records the comment count and reactions of the GitHub issue, both are returned along with the issue
+func (r *GithubIssueReconciler) RecordActivity(githubIssue *github.Issue, issueObject *issuesv1.GithubIssue) bool {
+	if githubIssue == nil {
+		return false
+	}
+	var reactions *issuesv1.IssueReactions
+	if ghReactions := githubIssue.GetReactions(); ghReactions != nil {
+		reactions = &issuesv1.IssueReactions{
+			TotalCount: ghReactions.GetTotalCount(),
+			PlusOne:    ghReactions.GetPlusOne(),
+			MinusOne:   ghReactions.GetMinusOne(),
+			Laugh:      ghReactions.GetLaugh(),
+			Confused:   ghReactions.GetConfused(),
+			Heart:      ghReactions.GetHeart(),
+			Hooray:     ghReactions.GetHooray(),
+			Rocket:     ghReactions.GetRocket(),
+			Eyes:       ghReactions.GetEyes(),
+		}
+	}
+	sameReactions := (reactions == nil) == (issueObject.Status.Reactions == nil) &&
+		(reactions == nil || *reactions == *issueObject.Status.Reactions)
+	if issueObject.Status.CommentCount == githubIssue.GetComments() && sameReactions {
+		return false
+	}
+	issueObject.Status.CommentCount = githubIssue.GetComments()
+	issueObject.Status.Reactions = reactions
+	return true
+}
+
+// MirrorComments records the latest comments of the GitHub issue in the status when mirrorComments is set.
+// Comments are only fetched when the issue was updated since the last sync
+func (r *GithubIssueReconciler) MirrorComments(ctx context.Context, githubIssue *github.Issue, issueObject *issuesv1.GithubIssue) (bool, error) {
+	limit := issueObject.Spec.MirrorComments
+	if limit == 0 {
+		changed := len(issueObject.Status.Comments) > 0 || issueObject.Status.LastSyncTime != nil ||
+			meta.FindStatusCondition(issueObject.Status.Conditions, "IssueHasNewComments") != nil
+		issueObject.Status.Comments = nil
+		issueObject.Status.LastSyncTime = nil
+		meta.RemoveStatusCondition(&issueObject.Status.Conditions, "IssueHasNewComments")
+		return changed, nil
+	}
+	if githubIssue == nil {
+		return false, nil
+	}
+	lastSync := issueObject.Status.LastSyncTime
+	expected := min(limit, githubIssue.GetComments())
+	if lastSync != nil && len(issueObject.Status.Comments) == expected && !githubIssue.GetUpdatedAt().After(lastSync.Time) {
+		return false, nil
+	}
+
+	owner, repo, err := repourl.Parse(issueObject.Spec.Repo)
+	if err != nil {
+		return false, err
+	}
+	ghComments, err := r.fetchLatestComments(ctx, owner, repo, githubIssue, limit)
+	if err != nil {
+		return false, err
+	}
+	comments := make([]issuesv1.IssueComment, 0, len(ghComments))
+	for _, ghComment := range ghComments {
+		comments = append(comments, issuesv1.IssueComment{
+			Author:    ghComment.GetUser().GetLogin(),
+			CreatedAt: v1.NewTime(ghComment.GetCreatedAt().Time),
+			Body:      truncate(ghComment.GetBody(), maxCommentBodyLength),
+		})
+	}
+	issueObject.Status.Comments = comments
+	now := v1.Now()
+	issueObject.Status.LastSyncTime = &now
+
+	condition := v1.Condition{Type: "IssueHasNewComments", Status: v1.ConditionFalse, Reason: "NoNewComments", Message: "No comments since the last sync"}
+	if lastSync != nil && len(comments) > 0 && comments[0].CreatedAt.After(lastSync.Time) {
+		condition = v1.Condition{Type: "IssueHasNewComments", Status: v1.ConditionTrue, Reason: "NewComments", Message: fmt.Sprintf("%s commented after the last sync", comments[0].Author)}
+	}
+	meta.SetStatusCondition(&issueObject.Status.Conditions, condition)
+	return true, nil
+}
+
+// fetchLatestComments gets the latest comments of the issue, newest first.
+// GitHub lists issue comments oldest first, so the last one or two pages are read based on the comment count
+func (r *GithubIssueReconciler) fetchLatestComments(ctx context.Context, owner string, repo string, githubIssue *github.Issue, limit int) ([]*github.IssueComment, error) {
+	count := githubIssue.GetComments()
+	if count == 0 {
+		return nil, nil
+	}
+	lastPage := (count + limit - 1) / limit
+	var comments []*github.IssueComment
+	for page := lastPage; page >= 1 && page >= lastPage-1 && len(comments) < limit; page-- {
+		opt := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{Page: page, PerPage: limit}}
+		pageComments, response, err := r.GitHubClient.Issues.ListComments(ctx, owner, repo, githubIssue.GetNumber(), opt)
+		if err != nil {
+			if response != nil {
+				return nil, fmt.Errorf("got bad response from GitHub: %s: %v", response.Status, err.Error())
+			}
+			return nil, fmt.Errorf("failed fetching comments: %v", err.Error())
+		}
+		for i := len(pageComments) - 1; i >= 0 && len(comments) < limit; i-- {
+			comments = append(comments, pageComments[i])
+		}
+	}
+	return comments, nil
+}
+
+// truncate shortens s to at most length characters
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length-1]) + "…"
+}
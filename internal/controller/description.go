@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	configMapIndexKey = "spec.descriptionFrom.configMapKeyRef.name"
+	secretIndexKey    = "spec.descriptionFrom.secretKeyRef.name"
+)
+
+// indexDescriptionConfigMap indexes GithubIssue CRDs by the ConfigMap their description is read from
+func indexDescriptionConfigMap(obj client.Object) []string {
+	issue := obj.(*issuesv1.GithubIssue)
+	if issue.Spec.DescriptionFrom == nil || issue.Spec.DescriptionFrom.ConfigMapKeyRef == nil {
+		return nil
+	}
+	return []string{issue.Spec.DescriptionFrom.ConfigMapKeyRef.Name}
+}
+
+// indexDescriptionSecret indexes GithubIssue CRDs by the Secret their description is read from
+func indexDescriptionSecret(obj client.Object) []string {
+	issue := obj.(*issuesv1.GithubIssue)
+	if issue.Spec.DescriptionFrom == nil || issue.Spec.DescriptionFrom.SecretKeyRef == nil {
+		return nil
+	}
+	return []string{issue.Spec.DescriptionFrom.SecretKeyRef.Name}
+}
+
+// issuesForConfigMap maps a ConfigMap to the GithubIssue CRDs reading their description from it
+func (r *GithubIssueReconciler) issuesForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.issuesMatchingIndex(ctx, obj, configMapIndexKey)
+}
+
+// issuesForSecret maps a Secret to the GithubIssue CRDs reading their description from it
+func (r *GithubIssueReconciler) issuesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.issuesMatchingIndex(ctx, obj, secretIndexKey)
+}
+
+func (r *GithubIssueReconciler) issuesMatchingIndex(ctx context.Context, obj client.Object, indexKey string) []reconcile.Request {
+	issueList := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, issueList, client.InNamespace(obj.GetNamespace()), client.MatchingFields{indexKey: obj.GetName()}); err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing issues referencing %s/%s: %v", obj.GetNamespace(), obj.GetName(), err.Error()))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(issueList.Items))
+	for _, issue := range issueList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: issue.Namespace, Name: issue.Name}})
+	}
+	return requests
+}
+
+// ResolveDescription returns the body of the issue, reading the referenced ConfigMap or Secret key if descriptionFrom is set
+func (r *GithubIssueReconciler) ResolveDescription(ctx context.Context, issueObject *issuesv1.GithubIssue) (string, error) {
+	source := issueObject.Spec.DescriptionFrom
+	if source == nil {
+		return issueObject.Spec.Description, nil
+	}
+	var content string
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		ref := source.ConfigMapKeyRef
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: issueObject.Namespace, Name: ref.Name}, configMap); err != nil {
+			if client.IgnoreNotFound(err) == nil && ref.Optional != nil && *ref.Optional {
+				break
+			}
+			return "", fmt.Errorf("failed fetching configmap %s: %v", ref.Name, err.Error())
+		}
+		value, ok := configMap.Data[ref.Key]
+		if !ok && (ref.Optional == nil || !*ref.Optional) {
+			return "", fmt.Errorf("key %s not found in configmap %s", ref.Key, ref.Name)
+		}
+		content = value
+	case source.SecretKeyRef != nil:
+		ref := source.SecretKeyRef
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: issueObject.Namespace, Name: ref.Name}, secret); err != nil {
+			if client.IgnoreNotFound(err) == nil && ref.Optional != nil && *ref.Optional {
+				break
+			}
+			return "", fmt.Errorf("failed fetching secret %s: %v", ref.Name, err.Error())
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok && (ref.Optional == nil || !*ref.Optional) {
+			return "", fmt.Errorf("key %s not found in secret %s", ref.Key, ref.Name)
+		}
+		content = string(value)
+	}
+	if issueObject.Spec.Description != "" {
+		if content != "" {
+			content += "\n\n"
+		}
+		content += issueObject.Spec.Description
+	}
+	return content, nil
+}
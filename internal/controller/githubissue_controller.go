@@ -18,15 +18,30 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/graphql"
+	"dvir.io/githubissue/internal/plan"
+	"dvir.io/githubissue/internal/policy"
+	"dvir.io/githubissue/internal/quota"
+	"dvir.io/githubissue/internal/render"
+	"dvir.io/githubissue/internal/repourl"
+	"dvir.io/githubissue/internal/safety"
 	"github.com/google/go-github/v56/github"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 // GithubIssueReconciler reconciles a GithubIssue object
@@ -35,13 +50,36 @@ type GithubIssueReconciler struct {
 	Scheme       *runtime.Scheme
 	Log          *zap.Logger
 	GitHubClient *github.Client
+	Recorder     record.EventRecorder
+	//DryRun plans the GitHub mutations of every GithubIssue CRD without making them
+	DryRun bool
+	//SyncInterval between refreshes of issues that do not set spec.syncInterval, they are not requeued when zero
+	SyncInterval time.Duration
+	//MinSyncInterval is the shortest sync interval an issue can ask for
+	MinSyncInterval time.Duration
+	//MaxConcurrentReconciles of different repositories, those of a repository are serialized
+	MaxConcurrentReconciles int
+	//Shard of the GithubIssue CRDs reconciled by this manager
+	Shard Shard
+	//Batcher fetches bound issues with the other issues of their repository through GraphQL, REST is used when nil
+	Batcher *graphql.Batcher
+	//Safety screens titles and bodies before they are sent to GitHub, nothing is screened when nil
+	Safety *safety.Scanner
+	//Quota limits the issues created per namespace, per repo and across the cluster, creates are not limited when nil
+	Quota *quota.Limiter
+
+	repoLocks repoLocks
+	//splitRepos holds the keys of the repositories already warned about by warnSplitRepo
+	splitRepos sync.Map
 }
 
 const CloseIssuesFinalizer = "issues.dvir.io/finalizer"
 
 //+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
 //+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
 
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.16.3/pkg/reconcile
@@ -57,31 +95,53 @@ func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, nil
 		}
 	}
-	splitUrl := strings.Split(issueObject.Spec.Repo, "/")
-	owner := splitUrl[3]
-	repo := splitUrl[4]
-	log.Info(fmt.Sprintf("attempting to get isues from %s/%s", owner, repo))
-	gitHubIssue, err := r.FindIssue(ctx, owner, repo, issueObject)
+	if !r.Shard.Owns(issueObject) {
+		return ctrl.Result{}, nil
+	}
+	owner, repo, err := repourl.Parse(issueObject.Spec.Repo)
 	if err != nil {
-		log.Error("failed fetching issue", zap.Error(err))
-		return ctrl.Result{}, err
+		log.Error("invalid repository url", zap.Error(err))
+		return ctrl.Result{}, nil
 	}
+	repoKey := strings.ToLower(owner + "/" + repo)
+	r.warnSplitRepo(ctx, issueObject, repoKey)
+	if !r.repoLocks.tryLock(repoKey) {
+		return ctrl.Result{RequeueAfter: repoBusyRetryPeriod}, nil
+	}
+	defer r.repoLocks.unlock(repoKey)
+	log.Info(fmt.Sprintf("attempting to get isues from %s/%s", owner, repo))
 	// Check if issues is being deleted
 	if !issueObject.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(issueObject, CloseIssuesFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		gitHubIssue, err := r.FindIssue(ctx, owner, repo, issueObject, searchTitle(issueObject))
+		if err != nil {
+			log.Error("failed fetching issue", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		if r.dryRun(issueObject) {
+			//Let the CRD go without touching the issue
+			p := plan.Close(gitHubIssue)
+			log.Info("dry run, not closing issue: " + p.String())
+			r.event(issueObject, corev1.EventTypeNormal, "DryRun", p.String())
+			_, err := r.DeleteFinalizer(ctx, issueObject)
+			return ctrl.Result{}, err
+		}
+		if r.suspended(ctx, issueObject) {
+			log.Info("suspended, not closing issue")
+			_, err := r.DeleteFinalizer(ctx, issueObject)
+			return ctrl.Result{}, err
+		}
 		//Issue is being deleted: close it
 		log.Info("closing issue")
 		if err := r.CloseIssue(ctx, owner, repo, gitHubIssue); err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed closing issue: %v", err.Error())
 		}
-		ok, err := r.DeleteFinalizer(ctx, issueObject)
-		if err != nil {
+		if _, err := r.DeleteFinalizer(ctx, issueObject); err != nil {
 			return ctrl.Result{}, err
-		} else {
-			if ok {
-				return ctrl.Result{}, nil
-			}
 		}
-
+		return ctrl.Result{}, nil
 	}
 	//Issue is not being deleted, add finalizer and search for it
 	err = r.AddFinalizer(ctx, issueObject)
@@ -90,60 +150,174 @@ func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	if err := policy.Check(ctx, r.Client, issueObject); err != nil {
+		denied := &policy.DeniedError{}
+		if !errors.As(err, &denied) {
+			log.Error("failed checking issue policies", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		//Do not create or edit issues that the policies of the namespace do not allow, report it and check again later
+		log.Info("issue denied by policy", zap.String("reason", err.Error()))
+		if r.CheckPolicy(err, issueObject) {
+			if statusErr := r.updateStatus(ctx, issueObject); statusErr != nil {
+				log.Error("error updating status ", zap.Error(statusErr))
+			}
+		}
+		return r.synced(issueObject), nil
+	}
+
+	desired, err := r.ResolveIssue(ctx, issueObject)
+	if err != nil {
+		var renderErr *render.Error
+		if !errors.As(err, &renderErr) {
+			log.Error("failed resolving issue", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		//Do not create or edit an issue from a broken template, report it and retry later
+		log.Error("failed rendering issue", zap.Error(err))
+		if r.CheckTemplate(err, issueObject) {
+			if statusErr := r.updateStatus(ctx, issueObject); statusErr != nil {
+				log.Error("error updating status ", zap.Error(statusErr))
+			}
+		}
+		return ctrl.Result{RequeueAfter: templateRetryPeriod}, nil
+	}
+	if err := r.ScreenContent(issueObject, desired); err != nil {
+		//Do not send content matching the safety rules, report it until the spec changes
+		log.Error("content blocked", zap.Error(err))
+		if r.CheckContent(err, issueObject) {
+			if statusErr := r.updateStatus(ctx, issueObject); statusErr != nil {
+				log.Error("error updating status ", zap.Error(statusErr))
+			}
+		}
+		return r.synced(issueObject), nil
+	}
+
+	gitHubIssue, err := r.FindIssue(ctx, owner, repo, issueObject, desired.Title)
+	if err != nil {
+		log.Error("failed fetching issue", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+
+	reason, _, err := r.suspension(ctx, issueObject)
+	if err != nil {
+		log.Error("failed checking suspension", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		//Keep the status fresh, but leave the issue to the humans working on it
+		log.Info("suspended, refreshing status only", zap.String("reason", reason))
+		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); err != nil {
+			log.Error("error updating status ", zap.Error(err))
+		}
+		return r.synced(issueObject), nil
+	}
+
+	if r.dryRun(issueObject) {
+		log.Info("dry run, planning issue mutations")
+		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); err != nil {
+			log.Error("error updating status ", zap.Error(err))
+		}
+		return r.synced(issueObject), nil
+	}
+
+	if gitHubIssue == nil && desired.State == "closed" {
+		//Nothing to close, do not open an issue only to close it
+		log.Info("issue is closed and does not exist, skipping")
+		return r.synced(issueObject), nil
+	}
+
 	if gitHubIssue == nil {
+		repoKey := strings.ToLower(owner + "/" + repo)
+		if err := r.Quota.Acquire(ctx, issueObject.Namespace, repoKey); err != nil {
+			if !errors.As(err, new(*quota.ExceededError)) {
+				log.Error("failed checking create quota", zap.Error(err))
+				return ctrl.Result{}, err
+			}
+			//Hold the create until the window moves on or the circuit breaker is reset
+			log.Error("create quota exceeded", zap.Error(err))
+			if r.CheckQuota(err, issueObject) {
+				r.event(issueObject, corev1.EventTypeWarning, "QuotaExceeded", err.Error())
+				if statusErr := r.updateStatus(ctx, issueObject); statusErr != nil {
+					log.Error("error updating status ", zap.Error(statusErr))
+				}
+			}
+			return r.quotaRetry(err, issueObject), nil
+		}
 
 		//Issue does not exist, create it
 		log.Info("creating issue")
-		err = r.CreateIssue(ctx, owner, repo, issueObject)
+		err = r.CreateIssue(ctx, owner, repo, desired)
 		if err != nil {
-			if statusErr := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue); err != nil {
+			if releaseErr := r.Quota.Release(ctx, issueObject.Namespace, repoKey); releaseErr != nil {
+				log.Error("failed releasing create quota", zap.Error(releaseErr))
+			}
+			if statusErr := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); statusErr != nil {
 				log.Error("error updating status ", zap.Error(statusErr))
 			}
 			return ctrl.Result{}, err
 		}
-		gitHubIssue, err = r.FindIssue(ctx, owner, repo, issueObject)
+		gitHubIssue, err = r.FindIssue(ctx, owner, repo, issueObject, desired.Title)
 		if err != nil {
 			log.Error("failed fetching issue", zap.Error(err))
 			return ctrl.Result{}, err
 		}
-		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue); err != nil {
+		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); err != nil {
 			log.Error("error updating status ", zap.Error(err))
 		}
 		log.Info("issue created")
-		return ctrl.Result{}, nil
+		return r.synced(issueObject), nil
 
 	} else {
 		//Issue exists, edit if needed and check for a PR
 		log.Info("editing issue")
 
-		if err := r.EditIssue(ctx, owner, repo, issueObject, *gitHubIssue.Number); err != nil {
-			gitHubIssue, issueErr := r.FindIssue(ctx, owner, repo, issueObject)
+		if err := r.EditIssue(ctx, owner, repo, desired, *gitHubIssue.Number); err != nil {
+			gitHubIssue, issueErr := r.FindIssue(ctx, owner, repo, issueObject, desired.Title)
 			if issueErr != nil {
 				log.Error("failed fetching issue", zap.Error(err))
 				return ctrl.Result{}, err
 			}
-			if statusErr := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue); statusErr != nil {
+			if statusErr := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); statusErr != nil {
 				log.Error("error updating status ", zap.Error(err))
 			}
 			return ctrl.Result{}, err
 		}
-		gitHubIssue, err := r.FindIssue(ctx, owner, repo, issueObject)
+		gitHubIssue, err := r.FindIssue(ctx, owner, repo, issueObject, desired.Title)
 		if err != nil {
 			log.Error("failed fetching issue", zap.Error(err))
 			return ctrl.Result{}, err
 		}
-		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue); err != nil {
+		if err := r.UpdateIssueStatus(ctx, issueObject, gitHubIssue, desired); err != nil {
 			log.Error("error updating status ", zap.Error(err))
 		}
 		log.Info("issue edited")
-		return ctrl.Result{}, nil
+		return r.synced(issueObject), nil
 	}
 
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GithubIssueReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &issuesv1.GithubIssue{}, configMapIndexKey, indexDescriptionConfigMap); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &issuesv1.GithubIssue{}, secretIndexKey, indexDescriptionSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &issuesv1.GithubIssue{}, milestoneIndexKey, indexMilestone); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &issuesv1.GithubIssue{}, repoIndexKey, indexRepo); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&issuesv1.GithubIssue{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.issuesForConfigMap)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.issuesForSecret)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.issuesForNamespace)).
+		Watches(&issuesv1.GithubMilestone{}, handler.EnqueueRequestsFromMapFunc(r.issuesForMilestone)).
 		Complete(r)
 }
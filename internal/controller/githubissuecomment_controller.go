@@ -0,0 +1,372 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"dvir.io/githubissue/internal/safety"
+	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// GithubIssueCommentReconciler reconciles a GithubIssueComment object
+type GithubIssueCommentReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	Log          *zap.Logger
+	GitHubClient *github.Client
+	//Safety screens comment bodies before they are sent to GitHub, nothing is screened when nil
+	Safety *safety.Scanner
+	//DryRun plans the GitHub mutations of every GithubIssueComment CRD without making them
+	DryRun   bool
+	Recorder record.EventRecorder
+}
+
+const DeleteCommentFinalizer = "issues.dvir.io/comment-finalizer"
+
+// commentResyncPeriod is how often posted comments are checked for drift, they can be edited or deleted on GitHub at any time
+const commentResyncPeriod = time.Minute
+
+const issueRefIndexKey = "spec.issueRef.name"
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuecomments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuecomments/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuecomments/finalizers,verbs=update
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues,verbs=get;list;watch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// Reconcile posts the comment on the referenced issue and keeps its body in sync with the spec
+func (r *GithubIssueCommentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log
+	comment := &issuesv1.GithubIssueComment{}
+	if err := r.Get(ctx, req.NamespacedName, comment); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error("unable to fetch comment object", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Check if comment is being deleted
+	if !comment.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(comment, DeleteCommentFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if reason, _, err := r.targetSuspension(ctx, comment, comment.Status.Repo, comment.Status.IssueNumber); err != nil || reason != "" {
+			//Let the CRD go without touching the issue, failing closed
+			log.Info("issue suspended, not deleting comment")
+		} else if r.DryRun {
+			//Let the CRD go without touching the issue
+			if comment.Status.CommentID != 0 {
+				planned := fmt.Sprintf("delete comment %d", comment.Status.CommentID)
+				log.Info("dry run, not deleting comment: " + planned)
+				recordEvent(r.Recorder, comment, corev1.EventTypeNormal, "DryRun", planned)
+			}
+		} else {
+			log.Info("deleting comment")
+			if err := r.DeleteComment(ctx, comment); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		controllerutil.RemoveFinalizer(comment, DeleteCommentFinalizer)
+		if err := r.Update(ctx, comment); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed removing finalizer: %v", err.Error())
+		}
+		return ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(comment, DeleteCommentFinalizer) {
+		controllerutil.AddFinalizer(comment, DeleteCommentFinalizer)
+		if err := r.Update(ctx, comment); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to add finalizer: %v", err.Error())
+		}
+	}
+
+	repoURL, number, err := r.ResolveTarget(ctx, comment)
+	if err != nil {
+		log.Error("failed resolving issue to comment on", zap.Error(err))
+		r.setSynced(comment, metav1.ConditionFalse, "IssueNotFound", err.Error())
+		if statusErr := r.updateStatus(ctx, comment); statusErr != nil {
+			log.Error("error updating status ", zap.Error(statusErr))
+		}
+		return ctrl.Result{}, err
+	}
+	if number == 0 {
+		//The referenced GithubIssue is watched, the comment is posted once it is bound
+		log.Info("waiting for issue to be bound")
+		if r.setSynced(comment, metav1.ConditionFalse, "IssueNotBound", "Referenced issue is not bound to a GitHub issue yet") {
+			return ctrl.Result{}, r.updateStatus(ctx, comment)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	reason, message, err := r.targetSuspension(ctx, comment, repoURL, number)
+	if err != nil {
+		log.Error("failed checking suspension", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		//Namespace labels are not watched, resuming is picked up on the next resync
+		log.Info("issue suspended, not syncing comment", zap.String("reason", reason))
+		if r.setSynced(comment, metav1.ConditionFalse, reason, message) {
+			return ctrl.Result{RequeueAfter: commentResyncPeriod}, r.updateStatus(ctx, comment)
+		}
+		return ctrl.Result{RequeueAfter: commentResyncPeriod}, nil
+	}
+
+	denied, err := targetDenied(ctx, r.Client, comment.Namespace, repoURL, nil)
+	if err != nil {
+		log.Error("failed checking issue policies", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if denied != nil {
+		//Do not comment on repos that the policies of the namespace do not allow, report it and check again later
+		log.Info("comment denied by policy", zap.String("reason", denied.Error()))
+		if r.setSynced(comment, metav1.ConditionFalse, "PolicyDenied", denied.Error()) {
+			return ctrl.Result{RequeueAfter: commentResyncPeriod}, r.updateStatus(ctx, comment)
+		}
+		return ctrl.Result{RequeueAfter: commentResyncPeriod}, nil
+	}
+
+	//The comment moved to another issue, remove it from the previous one
+	moved := comment.Status.CommentID != 0 && (comment.Status.Repo != repoURL || comment.Status.IssueNumber != number)
+	if moved && r.DryRun {
+		return r.recordPlan(ctx, comment, fmt.Sprintf("move comment %d to %s#%d", comment.Status.CommentID, repoURL, number))
+	}
+	if moved {
+		log.Info("issue changed, deleting previous comment")
+		if err := r.DeleteComment(ctx, comment); err != nil {
+			return ctrl.Result{}, err
+		}
+		comment.Status.CommentID = 0
+		comment.Status.URL = ""
+	}
+
+	body := comment.Spec.Body
+	rules, err := r.Safety.Screen(&body)
+	if err != nil {
+		//Do not post content matching the safety rules, report it until the spec changes
+		log.Error("content blocked", zap.Error(err))
+		r.setSynced(comment, metav1.ConditionFalse, "ContentBlocked", err.Error())
+		meta.SetStatusCondition(&comment.Status.Conditions, metav1.Condition{Type: "ContentBlocked", Status: metav1.ConditionTrue, Reason: "SafetyRuleMatched", Message: err.Error()})
+		return ctrl.Result{}, r.updateStatus(ctx, comment)
+	}
+	if len(rules) > 0 {
+		log.Info(fmt.Sprintf("redacted content matching %s", strings.Join(rules, ", ")))
+	}
+	meta.RemoveStatusCondition(&comment.Status.Conditions, "ContentBlocked")
+
+	planned, err := r.SyncComment(ctx, comment, body, repoURL, number)
+	if err != nil {
+		log.Error("failed syncing comment", zap.Error(err))
+		r.setSynced(comment, metav1.ConditionFalse, "SyncFailed", err.Error())
+		if statusErr := r.updateStatus(ctx, comment); statusErr != nil {
+			log.Error("error updating status ", zap.Error(statusErr))
+		}
+		return ctrl.Result{}, err
+	}
+	if planned != "" {
+		return r.recordPlan(ctx, comment, planned)
+	}
+	r.setSynced(comment, metav1.ConditionTrue, "CommentSynced", "Comment is in sync")
+	if err := r.updateStatus(ctx, comment); err != nil {
+		log.Error("error updating status ", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: commentResyncPeriod}, nil
+}
+
+// ResolveTarget returns the repo url and number of the issue to comment on. The number is 0 while the referenced GithubIssue is not bound
+func (r *GithubIssueCommentReconciler) ResolveTarget(ctx context.Context, comment *issuesv1.GithubIssueComment) (string, int, error) {
+	if comment.Spec.IssueRef == nil {
+		return comment.Spec.Repo, comment.Spec.Number, nil
+	}
+	issue := &issuesv1.GithubIssue{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: comment.Namespace, Name: comment.Spec.IssueRef.Name}, issue); err != nil {
+		return "", 0, fmt.Errorf("failed fetching issue %s: %v", comment.Spec.IssueRef.Name, err.Error())
+	}
+	return issue.Spec.Repo, boundIssueNumber(issue), nil
+}
+
+// targetSuspension returns why writes to the issue the comment targets are suspended, or an empty reason. The issue is
+// suspended by the GithubIssue objects bound to it, whichever namespace they are in, and by the namespace of the comment
+func (r *GithubIssueCommentReconciler) targetSuspension(ctx context.Context, comment *issuesv1.GithubIssueComment, repoURL string, number int) (string, string, error) {
+	if key, err := repourl.Key(repoURL); err == nil && number != 0 {
+		issues := &issuesv1.GithubIssueList{}
+		if err := r.List(ctx, issues); err != nil {
+			return "", "", fmt.Errorf("failed listing issues: %v", err.Error())
+		}
+		for i := range issues.Items {
+			issue := &issues.Items[i]
+			if issueKey, err := repourl.Key(issue.Spec.Repo); err != nil || issueKey != key || boundIssueNumber(issue) != number {
+				continue
+			}
+			reason, message, err := issueSuspension(ctx, r.Client, issue)
+			if err != nil || reason != "" {
+				return reason, fmt.Sprintf("issue %s/%s is suspended, %s", issue.Namespace, issue.Name, message), err
+			}
+		}
+	}
+	return namespaceSuspension(ctx, r.Client, comment.Namespace)
+}
+
+// SyncComment creates the comment on the issue, or edits it when its body differs from the screened body of the spec.
+// In a dry run it returns the planned mutation instead of making it
+func (r *GithubIssueCommentReconciler) SyncComment(ctx context.Context, comment *issuesv1.GithubIssueComment, body string, repoURL string, number int) (string, error) {
+	owner, repo, err := repourl.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if comment.Status.CommentID != 0 {
+		gitHubComment, response, err := r.GitHubClient.Issues.GetComment(ctx, owner, repo, comment.Status.CommentID)
+		switch {
+		case err == nil && gitHubComment.GetBody() == body:
+			r.recordComment(comment, gitHubComment, repoURL, number)
+			return "", nil
+		case err == nil && r.DryRun:
+			return fmt.Sprintf("edit comment %d on %s/%s#%d", comment.Status.CommentID, owner, repo, number), nil
+		case err == nil:
+			r.Log.Info("editing comment")
+			gitHubComment, _, err = r.GitHubClient.Issues.EditComment(ctx, owner, repo, comment.Status.CommentID, &github.IssueComment{Body: &body})
+			if err != nil {
+				return "", fmt.Errorf("failed editing comment: %v", err.Error())
+			}
+			r.recordComment(comment, gitHubComment, repoURL, number)
+			return "", nil
+		case response != nil && response.StatusCode == http.StatusNotFound:
+			//Comment was deleted on GitHub, post it again
+			r.Log.Info("comment not found, recreating it")
+		default:
+			return "", fmt.Errorf("failed fetching comment: %v", err.Error())
+		}
+	}
+	if r.DryRun {
+		return fmt.Sprintf("create comment on %s/%s#%d", owner, repo, number), nil
+	}
+	r.Log.Info("creating comment")
+	gitHubComment, _, err := r.GitHubClient.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return "", fmt.Errorf("failed creating comment: %v", err.Error())
+	}
+	r.recordComment(comment, gitHubComment, repoURL, number)
+	return "", nil
+}
+
+// recordPlan records the GitHub mutation planned by a dry run in the CommentSynced condition and as an Event
+func (r *GithubIssueCommentReconciler) recordPlan(ctx context.Context, comment *issuesv1.GithubIssueComment, planned string) (ctrl.Result, error) {
+	r.Log.Info("dry run, not syncing comment: " + planned)
+	if !r.setSynced(comment, metav1.ConditionFalse, "DryRun", planned) {
+		return ctrl.Result{RequeueAfter: commentResyncPeriod}, nil
+	}
+	recordEvent(r.Recorder, comment, corev1.EventTypeNormal, "DryRun", planned)
+	return ctrl.Result{RequeueAfter: commentResyncPeriod}, r.updateStatus(ctx, comment)
+}
+
+// DeleteComment deletes the comment recorded in the status from GitHub. Comments already gone are ignored
+func (r *GithubIssueCommentReconciler) DeleteComment(ctx context.Context, comment *issuesv1.GithubIssueComment) error {
+	if comment.Status.CommentID == 0 {
+		return nil
+	}
+	owner, repo, err := repourl.Parse(comment.Status.Repo)
+	if err != nil {
+		return err
+	}
+	response, err := r.GitHubClient.Issues.DeleteComment(ctx, owner, repo, comment.Status.CommentID)
+	if err != nil && (response == nil || response.StatusCode != http.StatusNotFound) {
+		return fmt.Errorf("failed deleting comment: %v", err.Error())
+	}
+	return nil
+}
+
+func (r *GithubIssueCommentReconciler) recordComment(comment *issuesv1.GithubIssueComment, gitHubComment *github.IssueComment, repoURL string, number int) {
+	comment.Status.CommentID = gitHubComment.GetID()
+	comment.Status.URL = gitHubComment.GetHTMLURL()
+	comment.Status.Repo = repoURL
+	comment.Status.IssueNumber = number
+}
+
+// setSynced sets the CommentSynced condition, returning whether it changed
+func (r *GithubIssueCommentReconciler) setSynced(comment *issuesv1.GithubIssueComment, status metav1.ConditionStatus, reason string, message string) bool {
+	existing := meta.FindStatusCondition(comment.Status.Conditions, "CommentSynced")
+	if existing != nil && existing.Status == status && existing.Reason == reason && existing.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&comment.Status.Conditions, metav1.Condition{Type: "CommentSynced", Status: status, Reason: reason, Message: message})
+	return true
+}
+
+// updateStatus writes the status of the GithubIssueComment CRD
+func (r *GithubIssueCommentReconciler) updateStatus(ctx context.Context, comment *issuesv1.GithubIssueComment) error {
+	if err := r.Client.Status().Update(ctx, comment); err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, comment); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
+	}
+	return nil
+}
+
+// indexIssueRef indexes GithubIssueComment CRDs by the GithubIssue they reference
+func indexIssueRef(obj client.Object) []string {
+	comment := obj.(*issuesv1.GithubIssueComment)
+	if comment.Spec.IssueRef == nil {
+		return nil
+	}
+	return []string{comment.Spec.IssueRef.Name}
+}
+
+// commentsForIssue maps a GithubIssue to the GithubIssueComment CRDs referencing it
+func (r *GithubIssueCommentReconciler) commentsForIssue(ctx context.Context, obj client.Object) []reconcile.Request {
+	commentList := &issuesv1.GithubIssueCommentList{}
+	if err := r.List(ctx, commentList, client.InNamespace(obj.GetNamespace()), client.MatchingFields{issueRefIndexKey: obj.GetName()}); err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing comments referencing %s/%s: %v", obj.GetNamespace(), obj.GetName(), err.Error()))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(commentList.Items))
+	for _, comment := range commentList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: comment.Namespace, Name: comment.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubIssueCommentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &issuesv1.GithubIssueComment{}, issueRefIndexKey, indexIssueRef); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&issuesv1.GithubIssueComment{}).
+		Watches(&issuesv1.GithubIssue{}, handler.EnqueueRequestsFromMapFunc(r.commentsForIssue)).
+		Complete(r)
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"github.com/google/go-github/v56/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("githubIssueComment controller", func() {
+	Context("When commenting on a bound githubIssue", func() {
+		It("creates the comment and edits it to match the spec", func() {
+			ctx := context.Background()
+			testIssue := GenerateTestIssue()
+			testIssue.Status.Number = 7
+			testComment := &issuesv1.GithubIssueComment{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: testIssue.Namespace},
+				Spec: issuesv1.GithubIssueCommentSpec{
+					IssueRef: &issuesv1.IssueReference{Name: testIssue.Name},
+					Body:     "rollout started",
+				},
+			}
+			c, s, err := CreateFakeClient(testIssue, testComment)
+			Expect(err).To(BeNil())
+
+			var createdOn string
+			var edited *github.IssueComment
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.PostReposIssuesCommentsByOwnerByRepoByIssueNumber,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						createdOn = r.URL.Path
+						request := &github.IssueComment{}
+						Expect(json.NewDecoder(r.Body).Decode(request)).To(Succeed())
+						Expect(request.GetBody()).To(Equal("rollout started"))
+						w.WriteHeader(http.StatusCreated)
+						_, _ = w.Write(mock.MustMarshal(github.IssueComment{
+							ID:      github.Int64(42),
+							Body:    request.Body,
+							HTMLURL: github.String("https://github.com/test/test/issues/7#issuecomment-42"),
+						}))
+					}),
+				),
+				mock.WithRequestMatch(
+					mock.GetReposIssuesCommentsByOwnerByRepoByCommentId,
+					github.IssueComment{ID: github.Int64(42), Body: github.String("rollout started")},
+				),
+				mock.WithRequestMatchHandler(
+					mock.PatchReposIssuesCommentsByOwnerByRepoByCommentId,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						edited = &github.IssueComment{}
+						Expect(json.NewDecoder(r.Body).Decode(edited)).To(Succeed())
+						_, _ = w.Write(mock.MustMarshal(github.IssueComment{ID: github.Int64(42), Body: edited.Body}))
+					}),
+				),
+			)
+
+			ghClient := github.NewClient(MockClient)
+			r := &GithubIssueCommentReconciler{Client: c,
+				Scheme: s, Log: TestLog, GitHubClient: ghClient}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      testComment.Name,
+					Namespace: testComment.Namespace,
+				},
+			}
+
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(commentResyncPeriod))
+			Expect(createdOn).To(Equal("/repos/test/test/issues/7/comments"))
+
+			reconciled := &issuesv1.GithubIssueComment{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Finalizers).To(ContainElement(DeleteCommentFinalizer))
+			Expect(reconciled.Status.CommentID).To(Equal(int64(42)))
+			Expect(reconciled.Status.URL).To(Equal("https://github.com/test/test/issues/7#issuecomment-42"))
+			Expect(meta.IsStatusConditionTrue(reconciled.Status.Conditions, "CommentSynced")).To(BeTrue())
+
+			reconciled.Spec.Body = "rollout finished"
+			Expect(c.Update(ctx, reconciled)).To(Succeed())
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(edited).ToNot(BeNil())
+			Expect(edited.GetBody()).To(Equal("rollout finished"))
+		})
+
+		It("plans the comment in a dry run without posting it", func() {
+			ctx := context.Background()
+			testComment := &issuesv1.GithubIssueComment{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec:       issuesv1.GithubIssueCommentSpec{Repo: "https://github.com/test/test", Number: 7, Body: "rollout started"},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), testComment)
+			Expect(err).To(BeNil())
+
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.PostReposIssuesCommentsByOwnerByRepoByIssueNumber,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Fail("a dry run should not post the comment")
+					}),
+				),
+			)
+			recorder := record.NewFakeRecorder(10)
+			r := &GithubIssueCommentReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient), DryRun: true, Recorder: recorder}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: testComment.Name, Namespace: testComment.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			reconciled := &issuesv1.GithubIssueComment{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.CommentID).To(BeZero())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "CommentSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal("DryRun"))
+			Expect(recorder.Events).To(Receive(Equal("Normal DryRun create comment on test/test#7")))
+		})
+
+		It("does not write to an issue whose GithubIssue is suspended", func() {
+			ctx := context.Background()
+			testIssue := GenerateTestIssue()
+			testIssue.Namespace = "team-a"
+			testIssue.Status.Number = 7
+			testIssue.Spec.Suspend = true
+			testComment := &issuesv1.GithubIssueComment{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubIssueCommentSpec{
+					Repo:   "https://github.com/Test/test",
+					Number: 7,
+					Body:   "rollout started",
+				},
+			}
+			c, s, err := CreateFakeClient(testIssue, testComment)
+			Expect(err).To(BeNil())
+
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.PostReposIssuesCommentsByOwnerByRepoByIssueNumber,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Fail("comment must not be posted on a suspended issue")
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.DeleteReposIssuesCommentsByOwnerByRepoByCommentId,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Fail("comment must not be deleted from a suspended issue")
+					}),
+				),
+			)
+			r := &GithubIssueCommentReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: testComment.Name, Namespace: testComment.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(commentResyncPeriod))
+
+			reconciled := &issuesv1.GithubIssueComment{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "CommentSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("SpecSuspended"))
+
+			By("letting the comment go without deleting it from the suspended issue")
+			reconciled.Status.CommentID = 42
+			reconciled.Status.Repo = testComment.Spec.Repo
+			reconciled.Status.IssueNumber = 7
+			Expect(c.Update(ctx, reconciled)).To(Succeed())
+			Expect(c.Delete(ctx, reconciled)).To(Succeed())
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).ToNot(Succeed())
+		})
+
+		It("does not comment on repos that the policies of the namespace do not allow", func() {
+			ctx := context.Background()
+			testComment := &issuesv1.GithubIssueComment{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec:       issuesv1.GithubIssueCommentSpec{Repo: "https://github.com/test/test", Number: 7, Body: "rollout started"},
+			}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testComment.Namespace}}
+			policy := &issuesv1.GithubIssuePolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+				Spec:       issuesv1.GithubIssuePolicySpec{Repos: []string{"test/allowed"}},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), testComment, namespace, policy)
+			Expect(err).To(BeNil())
+
+			//Any GitHub call fails the reconcile
+			MockClient = mock.NewMockedHTTPClient()
+			r := &GithubIssueCommentReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: testComment.Name, Namespace: testComment.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(commentResyncPeriod))
+
+			reconciled := &issuesv1.GithubIssueComment{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.CommentID).To(BeZero())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "CommentSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("PolicyDenied"))
+			Expect(condition.Message).To(ContainSubstring("test/test"))
+		})
+	})
+})
@@ -0,0 +1,312 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/render"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ref "k8s.io/client-go/tools/reference"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Clock knows how to get the current time. It can be faked out in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// GithubIssueScheduleReconciler reconciles a GithubIssueSchedule object
+type GithubIssueScheduleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    *zap.Logger
+	Clock  Clock
+}
+
+// maxMissedSchedules bounds the number of missed ticks walked one by one, past it the latest missed tick is looked
+// up backwards from now, so a schedule suspended or down for long still files its latest tick
+const maxMissedSchedules = 100
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissueschedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissueschedules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissueschedules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile files a GithubIssue from the template at every tick of the schedule and cleans up closed ones
+func (r *GithubIssueScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log
+	schedule := &issuesv1.GithubIssueSchedule{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error("unable to fetch schedule object", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if !schedule.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	children := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, children, client.InNamespace(schedule.Namespace), client.MatchingLabels{issuesv1.ScheduleLabel: schedule.Name}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed listing issues of schedule: %v", err.Error())
+	}
+	var open, closed []*issuesv1.GithubIssue
+	for i := range children.Items {
+		child := &children.Items[i]
+		if !metav1.IsControlledBy(child, schedule) || !child.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if meta.IsStatusConditionFalse(child.Status.Conditions, "IssueIsOpen") {
+			closed = append(closed, child)
+		} else {
+			open = append(open, child)
+		}
+	}
+
+	status := schedule.Status.DeepCopy()
+	status.Active = nil
+	for _, child := range open {
+		childRef, err := ref.GetReference(r.Scheme, child)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to make reference to issue %s: %v", child.Name, err.Error())
+		}
+		status.Active = append(status.Active, *childRef)
+	}
+
+	if err := r.pruneHistory(ctx, schedule, closed); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if schedule.Spec.Suspend {
+		log.Info("schedule suspended, skipping")
+		return ctrl.Result{}, r.updateScheduleStatus(ctx, schedule, status)
+	}
+
+	now := r.Clock.Now()
+	missedRun, nextRun, err := r.nextSchedules(schedule, now)
+	if err != nil {
+		log.Error("unable to figure out schedule", zap.Error(err))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Scheduled", Status: metav1.ConditionFalse, Reason: "InvalidSchedule", Message: err.Error()})
+		//Not requeued, the schedule has to be fixed first
+		return ctrl.Result{}, r.updateScheduleStatus(ctx, schedule, status)
+	}
+	result := ctrl.Result{RequeueAfter: nextRun.Sub(now)}
+	if missedRun.IsZero() {
+		return result, r.updateScheduleStatus(ctx, schedule, status)
+	}
+
+	deadline := schedule.Spec.StartingDeadlineSeconds
+	if deadline != nil && missedRun.Add(time.Duration(*deadline)*time.Second).Before(now) {
+		log.Info(fmt.Sprintf("missed starting deadline of %s, skipping", missedRun))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Scheduled", Status: metav1.ConditionFalse, Reason: "MissedDeadline", Message: fmt.Sprintf("Missed starting deadline of %s", missedRun.Format(time.RFC3339))})
+		return result, r.updateScheduleStatus(ctx, schedule, status)
+	}
+
+	switch schedule.Spec.ConcurrencyPolicy {
+	case issuesv1.ForbidConcurrent:
+		if len(open) > 0 {
+			log.Info("previous issue still open, skipping")
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Scheduled", Status: metav1.ConditionFalse, Reason: "PreviousIssueOpen", Message: fmt.Sprintf("Skipped %s, %s is still open", missedRun.Format(time.RFC3339), open[0].Name)})
+			return result, r.updateScheduleStatus(ctx, schedule, status)
+		}
+	case issuesv1.ReplaceConcurrent:
+		for _, child := range open {
+			//The finalizer of the GithubIssue closes the issue
+			if err := r.Delete(ctx, child, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, fmt.Errorf("failed replacing issue %s: %v", child.Name, err.Error())
+			}
+		}
+		status.Active = nil
+	}
+
+	child, err := r.issueForSchedule(schedule, missedRun)
+	if err != nil {
+		log.Error("failed rendering issue", zap.Error(err))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Scheduled", Status: metav1.ConditionFalse, Reason: "RenderFailed", Message: err.Error()})
+		return result, r.updateScheduleStatus(ctx, schedule, status)
+	}
+	if err := r.Create(ctx, child); err != nil && !apierrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, fmt.Errorf("failed creating issue %s: %v", child.Name, err.Error())
+	}
+	log.Info(fmt.Sprintf("filed issue %s for %s", child.Name, missedRun))
+	childRef, err := ref.GetReference(r.Scheme, child)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to make reference to issue %s: %v", child.Name, err.Error())
+	}
+	status.Active = append(status.Active, *childRef)
+	status.LastScheduleTime = &metav1.Time{Time: missedRun}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Scheduled", Status: metav1.ConditionTrue, Reason: "IssueFiled", Message: fmt.Sprintf("Filed %s", child.Name)})
+	return result, r.updateScheduleStatus(ctx, schedule, status)
+}
+
+// nextSchedules returns the latest tick missed since the last issue was filed, zero if none, and the next tick
+func (r *GithubIssueScheduleReconciler) nextSchedules(schedule *issuesv1.GithubIssueSchedule, now time.Time) (time.Time, time.Time, error) {
+	spec := schedule.Spec.Schedule
+	if schedule.Spec.TimeZone != nil {
+		spec = fmt.Sprintf("CRON_TZ=%s %s", *schedule.Spec.TimeZone, spec)
+	}
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", schedule.Spec.Schedule, err.Error())
+	}
+
+	var earliest time.Time
+	if schedule.Status.LastScheduleTime != nil {
+		earliest = schedule.Status.LastScheduleTime.Time
+	} else {
+		earliest = schedule.ObjectMeta.CreationTimestamp.Time
+	}
+	if deadline := schedule.Spec.StartingDeadlineSeconds; deadline != nil {
+		if start := now.Add(-time.Duration(*deadline) * time.Second); start.After(earliest) {
+			earliest = start
+		}
+	}
+	if earliest.After(now) {
+		return time.Time{}, sched.Next(now), nil
+	}
+
+	var lastMissed time.Time
+	missed := 0
+	for t := sched.Next(earliest); !t.After(now); t = sched.Next(t) {
+		lastMissed = t
+		missed++
+		if missed > maxMissedSchedules {
+			return latestTick(sched, t, now), sched.Next(now), nil
+		}
+	}
+	return lastMissed, sched.Next(now), nil
+}
+
+// latestTick returns the latest tick of the schedule that is not after now, given a tick at or before now.
+// It looks back from now over a window doubled until it holds a tick, then walks that window
+func latestTick(sched cron.Schedule, tick time.Time, now time.Time) time.Time {
+	lookback := sched.Next(tick).Sub(tick)
+	for lookback < now.Sub(tick) && sched.Next(now.Add(-lookback)).After(now) {
+		lookback *= 2
+	}
+	start := now.Add(-lookback)
+	if start.Before(tick) {
+		start = tick
+	}
+	latest := tick
+	for t := sched.Next(start); !t.After(now); t = sched.Next(t) {
+		latest = t
+	}
+	return latest
+}
+
+// issueForSchedule builds the GithubIssue filed for the scheduled time
+func (r *GithubIssueScheduleReconciler) issueForSchedule(schedule *issuesv1.GithubIssueSchedule, scheduledTime time.Time) (*issuesv1.GithubIssue, error) {
+	template := schedule.Spec.Template
+	data := map[string]interface{}{"Date": scheduledTime.Format(time.DateOnly), "Time": scheduledTime}
+	title, err := render.Render("title", template.Title, data)
+	if err != nil {
+		return nil, err
+	}
+	description, err := render.Render("description", template.Description, data)
+	if err != nil {
+		return nil, err
+	}
+
+	child := &issuesv1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{
+			// Deterministic per tick, so a tick is never filed twice
+			Name:        fmt.Sprintf("%s-%d", schedule.Name, scheduledTime.Unix()/60),
+			Namespace:   schedule.Namespace,
+			Labels:      mergeMaps(nil, template.Metadata.Labels),
+			Annotations: mergeMaps(nil, template.Metadata.Annotations),
+		},
+		Spec: issuesv1.GithubIssueSpec{
+			Repo:        template.Repo,
+			Title:       title,
+			Description: description,
+			Labels:      template.Labels,
+			Assignees:   template.Assignees,
+		},
+	}
+	child.Labels[issuesv1.ScheduleLabel] = schedule.Name
+	child.Annotations[issuesv1.ScheduledAtAnnotation] = scheduledTime.Format(time.RFC3339)
+	if err := controllerutil.SetControllerReference(schedule, child, r.Scheme); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// pruneHistory deletes the oldest closed issues beyond the history limit
+func (r *GithubIssueScheduleReconciler) pruneHistory(ctx context.Context, schedule *issuesv1.GithubIssueSchedule, closed []*issuesv1.GithubIssue) error {
+	limit := int32(3)
+	if schedule.Spec.ClosedHistoryLimit != nil {
+		limit = *schedule.Spec.ClosedHistoryLimit
+	}
+	if int32(len(closed)) <= limit {
+		return nil
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		return closed[i].Annotations[issuesv1.ScheduledAtAnnotation] < closed[j].Annotations[issuesv1.ScheduledAtAnnotation]
+	})
+	for _, child := range closed[:int32(len(closed))-limit] {
+		r.Log.Info(fmt.Sprintf("deleting old closed issue %s", child.Name))
+		if err := r.Delete(ctx, child, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed deleting issue %s: %v", child.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// updateScheduleStatus writes the status of the GithubIssueSchedule CRD when it changed
+func (r *GithubIssueScheduleReconciler) updateScheduleStatus(ctx context.Context, schedule *issuesv1.GithubIssueSchedule, status *issuesv1.GithubIssueScheduleStatus) error {
+	if equality.Semantic.DeepEqual(*status, schedule.Status) {
+		return nil
+	}
+	schedule.Status = *status
+	if err := r.Client.Status().Update(ctx, schedule); err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, schedule); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubIssueScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&issuesv1.GithubIssueSchedule{}).
+		Owns(&issuesv1.GithubIssue{}).
+		Complete(r)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time { return c.now }
+
+func newTestSchedule(policy issuesv1.ConcurrencyPolicy) *issuesv1.GithubIssueSchedule {
+	timeZone := "UTC"
+	historyLimit := int32(1)
+	return &issuesv1.GithubIssueSchedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "review",
+			Namespace:         "default",
+			UID:               types.UID(RandomString()),
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)),
+		},
+		Spec: issuesv1.GithubIssueScheduleSpec{
+			Schedule:           "0 9 1 * *",
+			TimeZone:           &timeZone,
+			ConcurrencyPolicy:  policy,
+			ClosedHistoryLimit: &historyLimit,
+			Template: issuesv1.ScheduledIssueTemplate{
+				Repo:  "https://github.com/test/test",
+				Title: `Access review {{ .Time.Format "January 2006" }}`,
+			},
+		},
+	}
+}
+
+func newScheduledIssue(schedule *issuesv1.GithubIssueSchedule, name string, scheduledAt string, open bool) *issuesv1.GithubIssue {
+	state := metav1.ConditionFalse
+	if open {
+		state = metav1.ConditionTrue
+	}
+	issue := &issuesv1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   schedule.Namespace,
+			Labels:      map[string]string{issuesv1.ScheduleLabel: schedule.Name},
+			Annotations: map[string]string{issuesv1.ScheduledAtAnnotation: scheduledAt},
+		},
+		Spec: issuesv1.GithubIssueSpec{Repo: "https://github.com/test/test", Title: name},
+		Status: issuesv1.GithubIssueStatus{Conditions: []metav1.Condition{
+			{Type: "IssueIsOpen", Status: state, Reason: "IssueIsOpen", LastTransitionTime: metav1.Now()},
+		}},
+	}
+	issue.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(schedule, issuesv1.GroupVersion.WithKind("GithubIssueSchedule"))}
+	return issue
+}
+
+var _ = Describe("githubIssueSchedule controller", func() {
+	Context("When a tick is due", func() {
+		It("files the latest missed tick and prunes closed issues beyond the history limit", func() {
+			ctx := context.Background()
+			schedule := newTestSchedule(issuesv1.AllowConcurrent)
+			older := newScheduledIssue(schedule, "review-old", "2023-11-01T09:00:00Z", false)
+			old := newScheduledIssue(schedule, "review-previous", "2023-12-01T09:00:00Z", false)
+			c, s, err := CreateFakeClient(older, old, schedule)
+			Expect(err).To(BeNil())
+
+			now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+			r := &GithubIssueScheduleReconciler{Client: c, Scheme: s, Log: TestLog, Clock: fakeClock{now: now}}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: schedule.Name, Namespace: schedule.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC).Sub(now)))
+
+			children := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, children, client.MatchingLabels{issuesv1.ScheduleLabel: schedule.Name})).To(Succeed())
+			titles := []string{}
+			for _, child := range children.Items {
+				titles = append(titles, child.Spec.Title)
+			}
+			Expect(titles).To(ConsistOf("review-previous", "Access review March 2024"))
+
+			reconciled := &issuesv1.GithubIssueSchedule{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.LastScheduleTime.Time).To(BeTemporally("==", time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)))
+			Expect(reconciled.Status.Active).To(HaveLen(1))
+			Expect(meta.IsStatusConditionTrue(reconciled.Status.Conditions, "Scheduled")).To(BeTrue())
+		})
+
+		It("files the latest tick and requeues when more ticks were missed than are walked", func() {
+			ctx := context.Background()
+			schedule := newTestSchedule(issuesv1.AllowConcurrent)
+			schedule.Spec.Schedule = "*/5 * * * *"
+			schedule.Spec.Template.Title = `Sweep {{ .Time.Format "15:04" }}`
+			c, s, err := CreateFakeClient(&issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: schedule.Namespace}}, schedule)
+			Expect(err).To(BeNil())
+
+			now := time.Date(2024, 1, 16, 9, 2, 0, 0, time.UTC)
+			r := &GithubIssueScheduleReconciler{Client: c, Scheme: s, Log: TestLog, Clock: fakeClock{now: now}}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: schedule.Name, Namespace: schedule.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(3 * time.Minute))
+
+			children := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, children, client.MatchingLabels{issuesv1.ScheduleLabel: schedule.Name})).To(Succeed())
+			Expect(children.Items).To(HaveLen(1))
+			Expect(children.Items[0].Spec.Title).To(Equal("Sweep 09:00"))
+
+			reconciled := &issuesv1.GithubIssueSchedule{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.LastScheduleTime.Time).To(BeTemporally("==", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)))
+		})
+
+		It("skips the tick while the previous issue is open when concurrency is forbidden", func() {
+			ctx := context.Background()
+			schedule := newTestSchedule(issuesv1.ForbidConcurrent)
+			previous := newScheduledIssue(schedule, "review-previous", "2024-02-01T09:00:00Z", true)
+			c, s, err := CreateFakeClient(previous, schedule)
+			Expect(err).To(BeNil())
+
+			r := &GithubIssueScheduleReconciler{Client: c, Scheme: s, Log: TestLog, Clock: fakeClock{now: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: schedule.Name, Namespace: schedule.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			children := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, children, client.MatchingLabels{issuesv1.ScheduleLabel: schedule.Name})).To(Succeed())
+			Expect(children.Items).To(HaveLen(1))
+
+			reconciled := &issuesv1.GithubIssueSchedule{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.LastScheduleTime).To(BeNil())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "Scheduled")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal("PreviousIssueOpen"))
+		})
+	})
+})
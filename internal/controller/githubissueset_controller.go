@@ -0,0 +1,349 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/render"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// GithubIssueSetReconciler reconciles a GithubIssueSet object
+type GithubIssueSetReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	Log          *zap.Logger
+	GitHubClient *github.Client
+	//Defaulter applies the defaults the mutating webhook injects into GithubIssue objects, so children are compared
+	//against their defaulted spec instead of being updated on every reconcile. Children are not defaulted when nil
+	Defaulter webhook.CustomDefaulter
+}
+
+// orgResyncPeriod is how often org generators list the repositories of their organization
+const orgResyncPeriod = 10 * time.Minute
+
+// generatedRepo is a repository generated by a GithubIssueSet, exposed to the issue template
+type generatedRepo struct {
+	Repo  string
+	Owner string
+	Name  string
+}
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuesets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuesets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuesets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissues,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Reconcile stamps out a GithubIssue per generated repository and aggregates their status
+func (r *GithubIssueSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log
+	issueSet := &issuesv1.GithubIssueSet{}
+	if err := r.Get(ctx, req.NamespacedName, issueSet); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error("unable to fetch issue set object", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if !issueSet.ObjectMeta.DeletionTimestamp.IsZero() {
+		//Children are garbage collected, their finalizers close the issues
+		return ctrl.Result{}, nil
+	}
+	result := ctrl.Result{}
+	for _, generator := range issueSet.Spec.Generators {
+		if generator.Org != nil {
+			result.RequeueAfter = orgResyncPeriod
+		}
+	}
+
+	status := issueSet.Status.DeepCopy()
+	repos, err := r.GenerateRepos(ctx, issueSet)
+	if err != nil {
+		log.Error("failed generating repositories", zap.Error(err))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Generated", Status: metav1.ConditionFalse, Reason: "GenerateFailed", Message: err.Error()})
+		return result, r.updateSetStatus(ctx, issueSet, status, err)
+	}
+
+	children, failures, err := r.SyncChildren(ctx, issueSet, repos)
+	if err != nil {
+		log.Error("failed syncing issues", zap.Error(err))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Generated", Status: metav1.ConditionFalse, Reason: "SyncFailed", Message: err.Error()})
+		return result, r.updateSetStatus(ctx, issueSet, status, err)
+	}
+	var syncErr error
+	if len(failures) > 0 {
+		messages := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			messages = append(messages, failure.Error())
+		}
+		syncErr = fmt.Errorf("%d of %d issues failed to sync: %s", len(failures), len(repos), strings.Join(messages, "; "))
+		log.Error("failed syncing issues", zap.Error(syncErr))
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Generated", Status: metav1.ConditionFalse, Reason: "SyncFailed", Message: syncErr.Error()})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: "Generated", Status: metav1.ConditionTrue, Reason: "Generated", Message: fmt.Sprintf("%d issues generated", len(repos))})
+	}
+	status.Repos = len(repos)
+	status.Open, status.Closed, status.WithPR = 0, 0, 0
+	for _, child := range children {
+		if condition := meta.FindStatusCondition(child.Status.Conditions, "IssueIsOpen"); condition != nil {
+			if condition.Status == metav1.ConditionTrue {
+				status.Open++
+			} else {
+				status.Closed++
+			}
+		}
+		if meta.IsStatusConditionTrue(child.Status.Conditions, "IssueHasPR") {
+			status.WithPR++
+		}
+	}
+	return result, r.updateSetStatus(ctx, issueSet, status, syncErr)
+}
+
+// GenerateRepos runs the generators of the GithubIssueSet, deduplicating the repositories they generate
+func (r *GithubIssueSetReconciler) GenerateRepos(ctx context.Context, issueSet *issuesv1.GithubIssueSet) ([]generatedRepo, error) {
+	var urls []string
+	for _, generator := range issueSet.Spec.Generators {
+		switch {
+		case generator.List != nil:
+			urls = append(urls, generator.List.Repos...)
+		case generator.Namespaces != nil:
+			selector, err := metav1.LabelSelectorAsSelector(&generator.Namespaces.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace selector: %v", err.Error())
+			}
+			namespaces := &corev1.NamespaceList{}
+			if err := r.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+				return nil, fmt.Errorf("failed listing namespaces: %v", err.Error())
+			}
+			for _, namespace := range namespaces.Items {
+				if repoURL, ok := namespace.Annotations[issuesv1.RepoAnnotation]; ok {
+					urls = append(urls, repoURL)
+				}
+			}
+		case generator.Org != nil:
+			orgURLs, err := r.orgRepos(ctx, generator.Org)
+			if err != nil {
+				return nil, err
+			}
+			urls = append(urls, orgURLs...)
+		}
+	}
+
+	seen := map[string]bool{}
+	var repos []generatedRepo
+	for _, repoURL := range urls {
+		host, owner, name, err := repourl.Split(repoURL)
+		if err != nil {
+			r.Log.Error("skipping invalid repository url", zap.Error(err))
+			continue
+		}
+		key, _ := repourl.Key(repoURL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		repos = append(repos, generatedRepo{Repo: repourl.Join(host, owner, name), Owner: owner, Name: name})
+	}
+	return repos, nil
+}
+
+// orgRepos lists the urls of the repositories of an organization matching the generator
+func (r *GithubIssueSetReconciler) orgRepos(ctx context.Context, generator *issuesv1.OrgGenerator) ([]string, error) {
+	opt := &github.RepositoryListByOrgOptions{Type: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	var urls []string
+	for {
+		repos, response, err := r.GitHubClient.Repositories.ListByOrg(ctx, generator.Org, opt)
+		if err != nil {
+			if response != nil {
+				return nil, fmt.Errorf("got bad response from GitHub: %s: %v", response.Status, err.Error())
+			}
+			return nil, fmt.Errorf("failed fetching repositories of %s: %v", generator.Org, err.Error())
+		}
+		for _, repo := range repos {
+			if repo.GetArchived() && !generator.IncludeArchived {
+				continue
+			}
+			if !hasTopics(repo.Topics, generator.Topics) {
+				continue
+			}
+			urls = append(urls, repo.GetHTMLURL())
+		}
+		if response.NextPage == 0 {
+			return urls, nil
+		}
+		opt.Page = response.NextPage
+	}
+}
+
+func hasTopics(topics []string, required []string) bool {
+	for _, topic := range required {
+		if !slices.Contains(topics, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+// SyncChildren creates or updates a GithubIssue per repository and deletes the children of repositories no longer generated.
+// Children that fail to render, sync or delete are returned as failures, so one broken child does not hold back the others
+func (r *GithubIssueSetReconciler) SyncChildren(ctx context.Context, issueSet *issuesv1.GithubIssueSet, repos []generatedRepo) ([]issuesv1.GithubIssue, []error, error) {
+	existing := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, existing, client.InNamespace(issueSet.Namespace), client.MatchingLabels{issuesv1.IssueSetLabel: issueSet.Name}); err != nil {
+		return nil, nil, fmt.Errorf("failed listing issues of set: %v", err.Error())
+	}
+
+	desired := map[string]bool{}
+	var children []issuesv1.GithubIssue
+	var failures []error
+	for _, repo := range repos {
+		child := &issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: issueSetChildName(issueSet.Name, repo), Namespace: issueSet.Namespace}}
+		//Marked before rendering, so the child of a template that fails to render is kept rather than deleted
+		desired[child.Name] = true
+
+		template := issueSet.Spec.Template
+		title, err := render.Render("title", template.Title, repo)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("issue %s: %v", child.Name, err.Error()))
+			continue
+		}
+		description, err := render.Render("description", template.Description, repo)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("issue %s: %v", child.Name, err.Error()))
+			continue
+		}
+
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, child, func() error {
+			child.Labels = mergeMaps(child.Labels, template.Metadata.Labels)
+			child.Labels[issuesv1.IssueSetLabel] = issueSet.Name
+			child.Annotations = mergeMaps(child.Annotations, template.Metadata.Annotations)
+			child.Spec.Repo = repo.Repo
+			child.Spec.Title = title
+			child.Spec.Description = description
+			//Copied, the defaulter appends to them
+			child.Spec.Labels = append([]string(nil), template.Labels...)
+			child.Spec.Assignees = append([]string(nil), template.Assignees...)
+			if r.Defaulter != nil {
+				if err := r.Defaulter.Default(ctx, child); err != nil {
+					return err
+				}
+			}
+			return controllerutil.SetControllerReference(issueSet, child, r.Scheme)
+		})
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed syncing issue %s: %v", child.Name, err.Error()))
+			continue
+		}
+		children = append(children, *child)
+	}
+
+	for i := range existing.Items {
+		child := &existing.Items[i]
+		if desired[child.Name] {
+			continue
+		}
+		r.Log.Info(fmt.Sprintf("deleting issue %s of repository no longer generated", child.Name))
+		if err := r.Delete(ctx, child); client.IgnoreNotFound(err) != nil {
+			failures = append(failures, fmt.Errorf("failed deleting issue %s: %v", child.Name, err.Error()))
+		}
+	}
+	return children, failures, nil
+}
+
+// issueSetChildName names the GithubIssue of a repository after the set and a hash of the repository
+func issueSetChildName(setName string, repo generatedRepo) string {
+	hash := fnv.New32a()
+	key, _ := repourl.Key(repo.Repo)
+	_, _ = hash.Write([]byte(key))
+	return fmt.Sprintf("%s-%08x", setName, hash.Sum32())
+}
+
+func mergeMaps(dst map[string]string, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = map[string]string{}
+	}
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
+
+// updateSetStatus writes the status of the GithubIssueSet CRD when it changed, returning reconcileErr
+func (r *GithubIssueSetReconciler) updateSetStatus(ctx context.Context, issueSet *issuesv1.GithubIssueSet, status *issuesv1.GithubIssueSetStatus, reconcileErr error) error {
+	if equality.Semantic.DeepEqual(*status, issueSet.Status) {
+		return reconcileErr
+	}
+	issueSet.Status = *status
+	if err := r.Client.Status().Update(ctx, issueSet); err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, issueSet); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
+	}
+	return reconcileErr
+}
+
+// setsForNamespace maps a Namespace to every GithubIssueSet CRD with a namespace generator. Sets are enqueued whether
+// their selector matches the current labels or not, so a set also notices a namespace that stopped matching
+func (r *GithubIssueSetReconciler) setsForNamespace(ctx context.Context, _ client.Object) []reconcile.Request {
+	setList := &issuesv1.GithubIssueSetList{}
+	if err := r.List(ctx, setList); err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing issue sets: %v", err.Error()))
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, issueSet := range setList.Items {
+		for _, generator := range issueSet.Spec.Generators {
+			if generator.Namespaces == nil {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: issueSet.Namespace, Name: issueSet.Name}})
+			break
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubIssueSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&issuesv1.GithubIssueSet{}).
+		Owns(&issuesv1.GithubIssue{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.setsForNamespace)).
+		Complete(r)
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type defaulterFunc func(ctx context.Context, obj runtime.Object) error
+
+func (f defaulterFunc) Default(ctx context.Context, obj runtime.Object) error { return f(ctx, obj) }
+
+var _ = Describe("githubIssueSet controller", func() {
+	Context("When generating issues", func() {
+		It("stamps out an issue per repository, prunes stale ones and aggregates their status", func() {
+			ctx := context.Background()
+			issueSet := &issuesv1.GithubIssueSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "cve", Namespace: "default"},
+				Spec: issuesv1.GithubIssueSetSpec{
+					Generators: []issuesv1.IssueSetGenerator{
+						{List: &issuesv1.ListGenerator{Repos: []string{"https://github.com/test/api", "https://github.com/test/web"}}},
+						{Namespaces: &issuesv1.NamespaceGenerator{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}}}},
+					},
+					Template: issuesv1.IssueTemplate{
+						Title:       "Bump base image in {{ .Name }}",
+						Description: "{{ .Repo }} is affected",
+						Labels:      []string{"security"},
+					},
+				},
+			}
+			platform := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "platform",
+				Labels:      map[string]string{"team": "platform"},
+				Annotations: map[string]string{issuesv1.RepoAnnotation: "https://github.com/Test/API"},
+			}}
+			other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "other",
+				Annotations: map[string]string{issuesv1.RepoAnnotation: "https://github.com/test/other"},
+			}}
+			web := generatedRepo{Repo: "https://github.com/test/web", Owner: "test", Name: "web"}
+			existing := &issuesv1.GithubIssue{
+				ObjectMeta: metav1.ObjectMeta{Name: issueSetChildName("cve", web), Namespace: "default", Labels: map[string]string{issuesv1.IssueSetLabel: "cve"}},
+				Spec:       issuesv1.GithubIssueSpec{Repo: web.Repo, Title: "old title"},
+				Status: issuesv1.GithubIssueStatus{Conditions: []metav1.Condition{
+					{Type: "IssueIsOpen", Status: metav1.ConditionFalse, Reason: "Issueisclosed", LastTransitionTime: metav1.Now()},
+					{Type: "IssueHasPR", Status: metav1.ConditionTrue, Reason: "IssueHasPR", LastTransitionTime: metav1.Now()},
+				}},
+			}
+			stale := &issuesv1.GithubIssue{
+				ObjectMeta: metav1.ObjectMeta{Name: "cve-stale", Namespace: "default", Labels: map[string]string{issuesv1.IssueSetLabel: "cve"}},
+				Spec:       issuesv1.GithubIssueSpec{Repo: "https://github.com/test/removed", Title: "Bump base image in removed"},
+			}
+			c, s, err := CreateFakeClient(existing, issueSet, platform, other, stale)
+			Expect(err).To(BeNil())
+
+			r := &GithubIssueSetReconciler{Client: c, Scheme: s, Log: TestLog}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: issueSet.Name, Namespace: issueSet.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			children := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, children, client.MatchingLabels{issuesv1.IssueSetLabel: "cve"})).To(Succeed())
+			Expect(children.Items).To(HaveLen(2))
+			titles := map[string]string{}
+			for _, child := range children.Items {
+				titles[child.Spec.Repo] = child.Spec.Title
+				Expect(metav1.IsControlledBy(&child, issueSet)).To(BeTrue())
+				Expect(child.Spec.Labels).To(Equal([]string{"security"}))
+			}
+			Expect(titles).To(Equal(map[string]string{
+				"https://github.com/test/api": "Bump base image in api",
+				"https://github.com/test/web": "Bump base image in web",
+			}))
+
+			reconciled := &issuesv1.GithubIssueSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.Repos).To(Equal(2))
+			Expect(reconciled.Status.Closed).To(Equal(1))
+			Expect(reconciled.Status.WithPR).To(Equal(1))
+			Expect(meta.IsStatusConditionTrue(reconciled.Status.Conditions, "Generated")).To(BeTrue())
+		})
+
+		It("leaves defaulted children untouched", func() {
+			ctx := context.Background()
+			issueSet := &issuesv1.GithubIssueSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "cve", Namespace: "default"},
+				Spec: issuesv1.GithubIssueSetSpec{
+					Generators: []issuesv1.IssueSetGenerator{{List: &issuesv1.ListGenerator{Repos: []string{"https://github.com/test/api"}}}},
+					Template: issuesv1.IssueTemplate{
+						Title:  "Bump base image in {{ .Name }}",
+						Labels: []string{"security"},
+					},
+				},
+			}
+			c, s, err := CreateFakeClient(&issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}}, issueSet)
+			Expect(err).To(BeNil())
+
+			//Stands in for the mutating webhook, which the fake client does not run
+			defaulter := defaulterFunc(func(_ context.Context, obj runtime.Object) error {
+				issue := obj.(*issuesv1.GithubIssue)
+				if !strings.HasPrefix(issue.Spec.Title, "[team] ") {
+					issue.Spec.Title = "[team] " + issue.Spec.Title
+				}
+				if len(issue.Spec.Labels) < 2 {
+					issue.Spec.Labels = append(issue.Spec.Labels, "team")
+				}
+				return nil
+			})
+			r := &GithubIssueSetReconciler{Client: c, Scheme: s, Log: TestLog, Defaulter: defaulter}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: issueSet.Name, Namespace: issueSet.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			child := &issuesv1.GithubIssue{}
+			key := types.NamespacedName{Name: issueSetChildName("cve", generatedRepo{Repo: "https://github.com/test/api"}), Namespace: "default"}
+			Expect(c.Get(ctx, key, child)).To(Succeed())
+			Expect(child.Spec.Title).To(Equal("[team] Bump base image in api"))
+			Expect(child.Spec.Labels).To(Equal([]string{"security", "team"}))
+
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			resynced := &issuesv1.GithubIssue{}
+			Expect(c.Get(ctx, key, resynced)).To(Succeed())
+			Expect(resynced.ResourceVersion).To(Equal(child.ResourceVersion))
+		})
+
+		It("keeps syncing the other children when one fails and reports it", func() {
+			ctx := context.Background()
+			issueSet := &issuesv1.GithubIssueSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "cve", Namespace: "default"},
+				Spec: issuesv1.GithubIssueSetSpec{
+					Generators: []issuesv1.IssueSetGenerator{{List: &issuesv1.ListGenerator{Repos: []string{"https://github.com/test/api", "https://github.com/test/web"}}}},
+					Template:   issuesv1.IssueTemplate{Title: "Bump base image in {{ .Name }}"},
+				},
+			}
+			c, s, err := CreateFakeClient(&issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}}, issueSet)
+			Expect(err).To(BeNil())
+
+			defaulter := defaulterFunc(func(_ context.Context, obj runtime.Object) error {
+				if obj.(*issuesv1.GithubIssue).Spec.Repo == "https://github.com/test/api" {
+					return fmt.Errorf("no defaults for api")
+				}
+				return nil
+			})
+			r := &GithubIssueSetReconciler{Client: c, Scheme: s, Log: TestLog, Defaulter: defaulter}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: issueSet.Name, Namespace: issueSet.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).To(MatchError(ContainSubstring("1 of 2 issues failed to sync")))
+
+			children := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, children, client.MatchingLabels{issuesv1.IssueSetLabel: "cve"})).To(Succeed())
+			Expect(children.Items).To(HaveLen(1))
+			Expect(children.Items[0].Spec.Repo).To(Equal("https://github.com/test/web"))
+
+			reconciled := &issuesv1.GithubIssueSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "Generated")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("SyncFailed"))
+			Expect(condition.Message).To(ContainSubstring("no defaults for api"))
+		})
+	})
+
+	Context("When namespaces change", func() {
+		It("enqueues every set with a namespace generator", func() {
+			ctx := context.Background()
+			selecting := &issuesv1.GithubIssueSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "default"},
+				Spec: issuesv1.GithubIssueSetSpec{Generators: []issuesv1.IssueSetGenerator{
+					{Namespaces: &issuesv1.NamespaceGenerator{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}}}},
+				}},
+			}
+			listing := &issuesv1.GithubIssueSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "list", Namespace: "default"},
+				Spec: issuesv1.GithubIssueSetSpec{Generators: []issuesv1.IssueSetGenerator{
+					{List: &issuesv1.ListGenerator{Repos: []string{"https://github.com/test/api"}}},
+				}},
+			}
+			c, s, err := CreateFakeClient(&issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}}, selecting, listing)
+			Expect(err).To(BeNil())
+
+			r := &GithubIssueSetReconciler{Client: c, Scheme: s, Log: TestLog}
+			//The team label was just removed, the set must still drop the issue of the namespace
+			unlabeled := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform"}}
+			Expect(r.setsForNamespace(ctx, unlabeled)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "platform", Namespace: "default"}},
+			}))
+		})
+	})
+})
@@ -0,0 +1,249 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GithubLabelSetReconciler reconciles a GithubLabelSet object
+type GithubLabelSetReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	Log          *zap.Logger
+	GitHubClient *github.Client
+	//DryRun plans the GitHub mutations of every GithubLabelSet CRD without making them
+	DryRun   bool
+	Recorder record.EventRecorder
+}
+
+// labelSetResyncPeriod is how often the labels of a repository are checked for drift, they can be edited on GitHub at any time
+const labelSetResyncPeriod = 5 * time.Minute
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githublabelsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githublabelsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// Reconcile makes the labels of the repository match the GithubLabelSet
+func (r *GithubLabelSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log
+	labelSet := &issuesv1.GithubLabelSet{}
+	if err := r.Get(ctx, req.NamespacedName, labelSet); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error("unable to fetch label set object", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	owner, repo, err := repourl.Parse(labelSet.Spec.Repo)
+	if err != nil {
+		log.Error("invalid repository url", zap.Error(err))
+		return ctrl.Result{}, nil
+	}
+
+	reason, message, err := namespaceSuspension(ctx, r.Client, labelSet.Namespace)
+	if err != nil {
+		log.Error("failed checking suspension", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	suspendChanged := setSuspended(&labelSet.Status.Conditions, reason, message)
+	if reason != "" {
+		//Namespace labels are not watched, resuming is picked up on the next resync
+		log.Info("namespace suspended, not syncing labels", zap.String("reason", reason))
+		if suspendChanged {
+			if err := r.updateStatus(ctx, labelSet); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: labelSetResyncPeriod}, nil
+	}
+
+	names := make([]string, 0, len(labelSet.Spec.Labels))
+	for _, label := range labelSet.Spec.Labels {
+		names = append(names, label.Name)
+	}
+	denied, err := targetDenied(ctx, r.Client, labelSet.Namespace, labelSet.Spec.Repo, names)
+	if err != nil {
+		log.Error("failed checking issue policies", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+
+	var synced, pruned, planned []string
+	var syncErr error
+	if denied == nil {
+		log.Info(fmt.Sprintf("syncing labels of %s/%s", owner, repo))
+		synced, pruned, planned, syncErr = r.SyncLabels(ctx, owner, repo, labelSet)
+	}
+	condition := metav1.Condition{Type: "LabelsSynced", Status: metav1.ConditionTrue, Reason: "LabelsSynced", Message: fmt.Sprintf("%d labels in sync", len(synced))}
+	if denied != nil {
+		//Do not touch the labels of repos, or labels, that the policies of the namespace do not allow
+		log.Info("labels denied by policy", zap.String("reason", denied.Error()))
+		condition = metav1.Condition{Type: "LabelsSynced", Status: metav1.ConditionFalse, Reason: "PolicyDenied", Message: denied.Error()}
+	} else if syncErr != nil {
+		log.Error("failed syncing labels", zap.Error(syncErr))
+		condition = metav1.Condition{Type: "LabelsSynced", Status: metav1.ConditionFalse, Reason: "SyncFailed", Message: syncErr.Error()}
+	} else if len(planned) > 0 {
+		condition = metav1.Condition{Type: "LabelsSynced", Status: metav1.ConditionFalse, Reason: "DryRun", Message: strings.Join(planned, ", ")}
+		log.Info("dry run, not syncing labels: " + condition.Message)
+	}
+
+	existing := meta.FindStatusCondition(labelSet.Status.Conditions, "LabelsSynced")
+	if suspendChanged || existing == nil || existing.Status != condition.Status || existing.Message != condition.Message ||
+		!reflect.DeepEqual(labelSet.Status.Labels, synced) || !reflect.DeepEqual(labelSet.Status.Pruned, pruned) {
+		if condition.Reason == "DryRun" {
+			recordEvent(r.Recorder, labelSet, corev1.EventTypeNormal, "DryRun", condition.Message)
+		}
+		meta.SetStatusCondition(&labelSet.Status.Conditions, condition)
+		labelSet.Status.Labels = synced
+		labelSet.Status.Pruned = pruned
+		if err := r.updateStatus(ctx, labelSet); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+	return ctrl.Result{RequeueAfter: labelSetResyncPeriod}, nil
+}
+
+func (r *GithubLabelSetReconciler) updateStatus(ctx context.Context, labelSet *issuesv1.GithubLabelSet) error {
+	if err := r.Client.Status().Update(ctx, labelSet); err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, labelSet); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
+	}
+	return nil
+}
+
+// SyncLabels creates, updates and renames the labels of the repository, and deletes unlisted ones when pruning.
+// It returns the names of the labels in sync and of the pruned labels, and in a dry run the planned mutations instead of making them
+func (r *GithubLabelSetReconciler) SyncLabels(ctx context.Context, owner string, repo string, labelSet *issuesv1.GithubLabelSet) (synced []string, pruned []string, planned []string, err error) {
+	existingLabels, err := r.fetchAllLabels(ctx, owner, repo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	existing := make(map[string]*github.Label, len(existingLabels))
+	for _, label := range existingLabels {
+		existing[strings.ToLower(label.GetName())] = label
+	}
+
+	managed := map[string]bool{}
+	for _, desired := range labelSet.Spec.Labels {
+		managed[strings.ToLower(desired.Name)] = true
+		request := &github.Label{Name: github.String(desired.Name), Color: github.String(strings.ToLower(desired.Color)), Description: github.String(desired.Description)}
+
+		current, found := existing[strings.ToLower(desired.Name)]
+		if !found {
+			for _, previousName := range desired.PreviousNames {
+				if previous, ok := existing[strings.ToLower(previousName)]; ok {
+					current = previous
+					delete(existing, strings.ToLower(previousName))
+					break
+				}
+			}
+		}
+		changed := current != nil &&
+			(current.GetName() != desired.Name || !strings.EqualFold(current.GetColor(), desired.Color) || current.GetDescription() != desired.Description)
+		switch {
+		case current == nil && r.DryRun:
+			planned = append(planned, fmt.Sprintf("create label %s", desired.Name))
+			continue
+		case current == nil:
+			r.Log.Info(fmt.Sprintf("creating label %s", desired.Name))
+			if _, _, err := r.GitHubClient.Issues.CreateLabel(ctx, owner, repo, request); err != nil {
+				return synced, nil, nil, fmt.Errorf("failed creating label %s: %v", desired.Name, err.Error())
+			}
+		case changed && r.DryRun:
+			planned = append(planned, fmt.Sprintf("edit label %s", current.GetName()))
+			continue
+		case changed:
+			r.Log.Info(fmt.Sprintf("editing label %s", current.GetName()))
+			if _, _, err := r.GitHubClient.Issues.EditLabel(ctx, owner, repo, current.GetName(), request); err != nil {
+				return synced, nil, nil, fmt.Errorf("failed editing label %s: %v", current.GetName(), err.Error())
+			}
+		}
+		synced = append(synced, desired.Name)
+	}
+
+	if !labelSet.Spec.Prune {
+		return synced, nil, planned, nil
+	}
+	for _, label := range existingLabels {
+		name := label.GetName()
+		if managed[strings.ToLower(name)] {
+			continue
+		}
+		if _, stillExists := existing[strings.ToLower(name)]; !stillExists {
+			//Renamed above
+			continue
+		}
+		if r.DryRun {
+			planned = append(planned, fmt.Sprintf("delete label %s", name))
+			continue
+		}
+		r.Log.Info(fmt.Sprintf("pruning label %s", name))
+		if _, err := r.GitHubClient.Issues.DeleteLabel(ctx, owner, repo, name); err != nil {
+			return synced, pruned, nil, fmt.Errorf("failed deleting label %s: %v", name, err.Error())
+		}
+		pruned = append(pruned, name)
+	}
+	return synced, pruned, planned, nil
+}
+
+// fetchAllLabels gets all labels of the repo
+func (r *GithubLabelSetReconciler) fetchAllLabels(ctx context.Context, owner string, repo string) ([]*github.Label, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	var allLabels []*github.Label
+	for {
+		labels, response, err := r.GitHubClient.Issues.ListLabels(ctx, owner, repo, opt)
+		if err != nil {
+			if response != nil {
+				return nil, fmt.Errorf("got bad response from GitHub: %s: %v", response.Status, err.Error())
+			}
+			return nil, fmt.Errorf("failed fetching labels: %v", err.Error())
+		}
+		allLabels = append(allLabels, labels...)
+		if response.NextPage == 0 {
+			return allLabels, nil
+		}
+		opt.Page = response.NextPage
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubLabelSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&issuesv1.GithubLabelSet{}).
+		Complete(r)
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"github.com/google/go-github/v56/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("githubLabelSet controller", func() {
+	Context("When syncing repository labels", func() {
+		It("creates, edits, renames and prunes labels", func() {
+			ctx := context.Background()
+			labelSet := &issuesv1.GithubLabelSet{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubLabelSetSpec{
+					Repo:  "https://github.com/test/test",
+					Prune: true,
+					Labels: []issuesv1.LabelSpec{
+						{Name: "bug", Color: "D73A4A", Description: "Something isn't working"},
+						{Name: "needs-triage", Color: "fbca04", PreviousNames: []string{"triage"}},
+						{Name: "managed", Color: "0e8a16"},
+					},
+				},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), labelSet)
+			Expect(err).To(BeNil())
+
+			var created []string
+			edited := map[string]string{}
+			var deleted []string
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposLabelsByOwnerByRepo,
+					[]*github.Label{
+						{Name: github.String("bug"), Color: github.String("ff0000")},
+						{Name: github.String("triage"), Color: github.String("fbca04")},
+						{Name: github.String("wontfix"), Color: github.String("ffffff")},
+					},
+				),
+				mock.WithRequestMatchHandler(
+					mock.PostReposLabelsByOwnerByRepo,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						label := &github.Label{}
+						Expect(json.NewDecoder(r.Body).Decode(label)).To(Succeed())
+						created = append(created, label.GetName())
+						w.WriteHeader(http.StatusCreated)
+						_, _ = w.Write(mock.MustMarshal(label))
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.PatchReposLabelsByOwnerByRepoByName,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						label := &github.Label{}
+						Expect(json.NewDecoder(r.Body).Decode(label)).To(Succeed())
+						edited[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = label.GetName()
+						_, _ = w.Write(mock.MustMarshal(label))
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.DeleteReposLabelsByOwnerByRepoByName,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						deleted = append(deleted, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+						w.WriteHeader(http.StatusNoContent)
+					}),
+				),
+			)
+
+			ghClient := github.NewClient(MockClient)
+			r := &GithubLabelSetReconciler{Client: c,
+				Scheme: s, Log: TestLog, GitHubClient: ghClient}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      labelSet.Name,
+					Namespace: labelSet.Namespace,
+				},
+			}
+
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(Equal([]string{"managed"}))
+			Expect(edited).To(Equal(map[string]string{"bug": "bug", "triage": "needs-triage"}))
+			Expect(deleted).To(Equal([]string{"wontfix"}))
+
+			reconciled := &issuesv1.GithubLabelSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.Labels).To(Equal([]string{"bug", "needs-triage", "managed"}))
+			Expect(reconciled.Status.Pruned).To(Equal([]string{"wontfix"}))
+			Expect(meta.IsStatusConditionTrue(reconciled.Status.Conditions, "LabelsSynced")).To(BeTrue())
+		})
+
+		It("plans the label changes in a dry run without making them", func() {
+			ctx := context.Background()
+			labelSet := &issuesv1.GithubLabelSet{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubLabelSetSpec{
+					Repo:  "https://github.com/test/test",
+					Prune: true,
+					Labels: []issuesv1.LabelSpec{
+						{Name: "bug", Color: "d73a4a"},
+						{Name: "needs-triage", Color: "fbca04", PreviousNames: []string{"triage"}},
+						{Name: "managed", Color: "0e8a16"},
+					},
+				},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), labelSet)
+			Expect(err).To(BeNil())
+
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposLabelsByOwnerByRepo,
+					[]*github.Label{
+						{Name: github.String("bug"), Color: github.String("d73a4a")},
+						{Name: github.String("triage"), Color: github.String("fbca04")},
+						{Name: github.String("wontfix"), Color: github.String("ffffff")},
+					},
+				),
+			)
+			recorder := record.NewFakeRecorder(10)
+			r := &GithubLabelSetReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient), DryRun: true, Recorder: recorder}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: labelSet.Name, Namespace: labelSet.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			reconciled := &issuesv1.GithubLabelSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.Labels).To(Equal([]string{"bug"}))
+			Expect(reconciled.Status.Pruned).To(BeEmpty())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "LabelsSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal("DryRun"))
+			Expect(recorder.Events).To(Receive(Equal("Normal DryRun edit label triage, create label managed, delete label wontfix")))
+		})
+
+		It("does not sync labels that the policies of the namespace do not allow", func() {
+			ctx := context.Background()
+			labelSet := &issuesv1.GithubLabelSet{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubLabelSetSpec{
+					Repo:   "https://github.com/test/test",
+					Labels: []issuesv1.LabelSpec{{Name: "bug", Color: "d73a4a"}, {Name: "wontfix", Color: "ffffff"}},
+				},
+			}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: labelSet.Namespace}}
+			policy := &issuesv1.GithubIssuePolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+				Spec:       issuesv1.GithubIssuePolicySpec{Labels: []string{"bug"}},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), labelSet, namespace, policy)
+			Expect(err).To(BeNil())
+
+			//Any GitHub call fails the reconcile
+			MockClient = mock.NewMockedHTTPClient()
+			r := &GithubLabelSetReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: labelSet.Name, Namespace: labelSet.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			reconciled := &issuesv1.GithubLabelSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.Labels).To(BeEmpty())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "LabelsSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("PolicyDenied"))
+			Expect(condition.Message).To(ContainSubstring("wontfix"))
+		})
+
+		It("does not sync labels in a suspended namespace", func() {
+			ctx := context.Background()
+			labelSet := &issuesv1.GithubLabelSet{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubLabelSetSpec{
+					Repo:   "https://github.com/test/test",
+					Prune:  true,
+					Labels: []issuesv1.LabelSpec{{Name: "bug", Color: "d73a4a"}},
+				},
+			}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: labelSet.Namespace, Labels: map[string]string{issuesv1.SuspendedNamespaceLabel: "true"}}}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), labelSet, namespace)
+			Expect(err).To(BeNil())
+
+			//Any GitHub call fails the reconcile
+			MockClient = mock.NewMockedHTTPClient()
+			r := &GithubLabelSetReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: labelSet.Name, Namespace: labelSet.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(labelSetResyncPeriod))
+
+			reconciled := &issuesv1.GithubLabelSet{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "Suspended")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("NamespaceSuspended"))
+		})
+	})
+})
@@ -0,0 +1,306 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// GithubMilestoneReconciler reconciles a GithubMilestone object
+type GithubMilestoneReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	Log          *zap.Logger
+	GitHubClient *github.Client
+	//DryRun plans the GitHub mutations of every GithubMilestone CRD without making them
+	DryRun   bool
+	Recorder record.EventRecorder
+}
+
+const CloseMilestoneFinalizer = "issues.dvir.io/milestone-finalizer"
+
+// milestoneResyncPeriod is how often the issue counts of a milestone are refreshed.
+// Issues not managed by the operator can be added to the milestone or closed at any time
+const milestoneResyncPeriod = 5 * time.Minute
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubmilestones,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubmilestones/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubmilestones/finalizers,verbs=update
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// Reconcile creates the milestone on GitHub, keeps it in sync with the spec and reports its progress
+func (r *GithubMilestoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log
+	milestone := &issuesv1.GithubMilestone{}
+	if err := r.Get(ctx, req.NamespacedName, milestone); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error("unable to fetch milestone object", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	owner, repo, err := repourl.Parse(milestone.Spec.Repo)
+	if err != nil {
+		log.Error("invalid repository url", zap.Error(err))
+		return ctrl.Result{}, nil
+	}
+
+	reason, message, err := namespaceSuspension(ctx, r.Client, milestone.Namespace)
+	if err != nil {
+		log.Error("failed checking suspension", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+
+	// Check if milestone is being deleted
+	if !milestone.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(milestone, CloseMilestoneFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if milestone.Status.Number != 0 && reason != "" {
+			//Let the CRD go without closing the milestone from a suspended namespace
+			log.Info("namespace suspended, not closing milestone", zap.String("reason", reason))
+		} else if milestone.Status.Number != 0 && r.DryRun {
+			//Let the CRD go without touching the milestone
+			planned := fmt.Sprintf("close milestone #%d", milestone.Status.Number)
+			log.Info("dry run, not closing milestone: " + planned)
+			recordEvent(r.Recorder, milestone, corev1.EventTypeNormal, "DryRun", planned)
+		} else if milestone.Status.Number != 0 {
+			log.Info("closing milestone")
+			request := &github.Milestone{State: github.String("closed")}
+			_, response, err := r.GitHubClient.Issues.EditMilestone(ctx, owner, repo, milestone.Status.Number, request)
+			if err != nil && (response == nil || response.StatusCode != http.StatusNotFound) {
+				return ctrl.Result{}, fmt.Errorf("failed closing milestone: %v", err.Error())
+			}
+		}
+		controllerutil.RemoveFinalizer(milestone, CloseMilestoneFinalizer)
+		if err := r.Update(ctx, milestone); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed removing finalizer: %v", err.Error())
+		}
+		return ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(milestone, CloseMilestoneFinalizer) {
+		controllerutil.AddFinalizer(milestone, CloseMilestoneFinalizer)
+		if err := r.Update(ctx, milestone); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to add finalizer: %v", err.Error())
+		}
+	}
+
+	suspendChanged := setSuspended(&milestone.Status.Conditions, reason, message)
+	if reason != "" {
+		//Namespace labels are not watched, resuming is picked up on the next resync
+		log.Info("namespace suspended, not syncing milestone", zap.String("reason", reason))
+		if suspendChanged {
+			if err := r.updateStatus(ctx, milestone); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: milestoneResyncPeriod}, nil
+	}
+
+	denied, err := targetDenied(ctx, r.Client, milestone.Namespace, milestone.Spec.Repo, nil)
+	if err != nil {
+		log.Error("failed checking issue policies", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if denied != nil {
+		//Do not create or edit milestones in repos that the policies of the namespace do not allow, check again later
+		log.Info("milestone denied by policy", zap.String("reason", denied.Error()))
+		return ctrl.Result{RequeueAfter: milestoneResyncPeriod}, r.recordDenied(ctx, milestone, denied)
+	}
+
+	gitHubMilestone, planned, syncErr := r.SyncMilestone(ctx, owner, repo, milestone)
+	if syncErr != nil {
+		log.Error("failed syncing milestone", zap.Error(syncErr))
+	}
+	if planned != "" {
+		log.Info("dry run, not syncing milestone: " + planned)
+	}
+	if r.RecordMilestone(gitHubMilestone, planned, syncErr, milestone) || suspendChanged {
+		if planned != "" {
+			recordEvent(r.Recorder, milestone, corev1.EventTypeNormal, "DryRun", planned)
+		}
+		if err := r.updateStatus(ctx, milestone); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+	return ctrl.Result{RequeueAfter: milestoneResyncPeriod}, nil
+}
+
+// SyncMilestone finds the milestone bound to the GithubMilestone, adopting one with the same title, and creates or edits it to match the spec.
+// In a dry run it returns the planned mutation instead of making it
+func (r *GithubMilestoneReconciler) SyncMilestone(ctx context.Context, owner string, repo string, milestone *issuesv1.GithubMilestone) (*github.Milestone, string, error) {
+	current, err := r.findMilestone(ctx, owner, repo, milestone)
+	if err != nil {
+		return nil, "", err
+	}
+	state := milestone.Spec.State
+	if state == "" {
+		state = "open"
+	}
+	request := &github.Milestone{
+		Title:       github.String(milestone.Spec.Title),
+		Description: github.String(milestone.Spec.Description),
+		State:       github.String(state),
+	}
+	if milestone.Spec.DueOn != nil {
+		request.DueOn = &github.Timestamp{Time: milestone.Spec.DueOn.Time}
+	}
+
+	if current == nil && r.DryRun {
+		return nil, fmt.Sprintf("create milestone %q", milestone.Spec.Title), nil
+	}
+	if current == nil {
+		r.Log.Info("creating milestone")
+		created, _, err := r.GitHubClient.Issues.CreateMilestone(ctx, owner, repo, request)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed creating milestone: %v", err.Error())
+		}
+		return created, "", nil
+	}
+	if current.GetTitle() == milestone.Spec.Title && current.GetDescription() == milestone.Spec.Description &&
+		current.GetState() == state && sameDueDate(current.DueOn, milestone.Spec.DueOn) {
+		return current, "", nil
+	}
+	if r.DryRun {
+		return current, fmt.Sprintf("edit milestone #%d", current.GetNumber()), nil
+	}
+	r.Log.Info("editing milestone")
+	edited, _, err := r.GitHubClient.Issues.EditMilestone(ctx, owner, repo, current.GetNumber(), request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed editing milestone: %v", err.Error())
+	}
+	return edited, "", nil
+}
+
+// findMilestone gets the milestone bound to the GithubMilestone, falling back to searching the repo by title
+func (r *GithubMilestoneReconciler) findMilestone(ctx context.Context, owner string, repo string, milestone *issuesv1.GithubMilestone) (*github.Milestone, error) {
+	if milestone.Status.Number != 0 {
+		gitHubMilestone, response, err := r.GitHubClient.Issues.GetMilestone(ctx, owner, repo, milestone.Status.Number)
+		if err == nil {
+			return gitHubMilestone, nil
+		}
+		if response == nil || response.StatusCode != http.StatusNotFound {
+			return nil, fmt.Errorf("failed fetching milestone: %v", err.Error())
+		}
+		//Milestone was deleted on GitHub, search for it again
+	}
+	opt := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		milestones, response, err := r.GitHubClient.Issues.ListMilestones(ctx, owner, repo, opt)
+		if err != nil {
+			if response != nil {
+				return nil, fmt.Errorf("got bad response from GitHub: %s: %v", response.Status, err.Error())
+			}
+			return nil, fmt.Errorf("failed fetching milestones: %v", err.Error())
+		}
+		for _, gitHubMilestone := range milestones {
+			if strings.EqualFold(gitHubMilestone.GetTitle(), milestone.Spec.Title) {
+				return gitHubMilestone, nil
+			}
+		}
+		if response.NextPage == 0 {
+			return nil, nil
+		}
+		opt.Page = response.NextPage
+	}
+}
+
+// RecordMilestone records the binding and progress of the milestone in the status of the GithubMilestone CRD,
+// and the mutation planned by a dry run
+func (r *GithubMilestoneReconciler) RecordMilestone(gitHubMilestone *github.Milestone, planned string, syncErr error, milestone *issuesv1.GithubMilestone) bool {
+	status := milestone.Status.DeepCopy()
+	condition := metav1.Condition{Type: "MilestoneSynced", Status: metav1.ConditionTrue, Reason: "MilestoneSynced", Message: "Milestone is in sync"}
+	switch {
+	case syncErr != nil:
+		condition = metav1.Condition{Type: "MilestoneSynced", Status: metav1.ConditionFalse, Reason: "SyncFailed", Message: syncErr.Error()}
+	case planned != "":
+		condition = metav1.Condition{Type: "MilestoneSynced", Status: metav1.ConditionFalse, Reason: "DryRun", Message: planned}
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+	if gitHubMilestone != nil {
+		status.Number = gitHubMilestone.GetNumber()
+		status.URL = gitHubMilestone.GetHTMLURL()
+		status.OpenIssues = gitHubMilestone.GetOpenIssues()
+		status.ClosedIssues = gitHubMilestone.GetClosedIssues()
+		status.PercentComplete = 0
+		if total := status.OpenIssues + status.ClosedIssues; total > 0 {
+			status.PercentComplete = status.ClosedIssues * 100 / total
+		}
+	}
+	if equality.Semantic.DeepEqual(*status, milestone.Status) {
+		return false
+	}
+	milestone.Status = *status
+	return true
+}
+
+// recordDenied records the denial of the milestone by the policies of its namespace in the MilestoneSynced condition
+func (r *GithubMilestoneReconciler) recordDenied(ctx context.Context, milestone *issuesv1.GithubMilestone, denied error) error {
+	condition := metav1.Condition{Type: "MilestoneSynced", Status: metav1.ConditionFalse, Reason: "PolicyDenied", Message: denied.Error()}
+	existing := meta.FindStatusCondition(milestone.Status.Conditions, "MilestoneSynced")
+	if existing != nil && existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return nil
+	}
+	meta.SetStatusCondition(&milestone.Status.Conditions, condition)
+	return r.updateStatus(ctx, milestone)
+}
+
+func (r *GithubMilestoneReconciler) updateStatus(ctx context.Context, milestone *issuesv1.GithubMilestone) error {
+	if err := r.Client.Status().Update(ctx, milestone); err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, milestone); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
+	}
+	return nil
+}
+
+// sameDueDate compares due dates by day, GitHub drops the time of day
+func sameDueDate(current *github.Timestamp, desired *metav1.Time) bool {
+	if current == nil || desired == nil {
+		return current == nil && desired == nil
+	}
+	return current.UTC().Format(time.DateOnly) == desired.UTC().Format(time.DateOnly)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubMilestoneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&issuesv1.GithubMilestone{}).
+		Complete(r)
+}
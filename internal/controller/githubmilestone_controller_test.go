@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"github.com/google/go-github/v56/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("githubMilestone controller", func() {
+	Context("When creating a githubMilestone", func() {
+		It("adopts the milestone with the same title and reports its progress", func() {
+			ctx := context.Background()
+			milestone := &issuesv1.GithubMilestone{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec: issuesv1.GithubMilestoneSpec{
+					Repo:  "https://github.com/test/test",
+					Title: "v1.0",
+					State: "open",
+				},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), milestone)
+			Expect(err).To(BeNil())
+
+			var edited *github.Milestone
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposMilestonesByOwnerByRepo,
+					[]*github.Milestone{
+						{Number: github.Int(1), Title: github.String("v0.9"), State: github.String("closed")},
+						{Number: github.Int(2), Title: github.String("V1.0"), State: github.String("open")},
+					},
+				),
+				mock.WithRequestMatchHandler(
+					mock.PatchReposMilestonesByOwnerByRepoByMilestoneNumber,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						edited = &github.Milestone{}
+						Expect(json.NewDecoder(r.Body).Decode(edited)).To(Succeed())
+						Expect(r.URL.Path).To(Equal("/repos/test/test/milestones/2"))
+						_, _ = w.Write(mock.MustMarshal(github.Milestone{
+							Number:       github.Int(2),
+							Title:        edited.Title,
+							State:        edited.State,
+							OpenIssues:   github.Int(1),
+							ClosedIssues: github.Int(3),
+						}))
+					}),
+				),
+			)
+
+			ghClient := github.NewClient(MockClient)
+			r := &GithubMilestoneReconciler{Client: c,
+				Scheme: s, Log: TestLog, GitHubClient: ghClient}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      milestone.Name,
+					Namespace: milestone.Namespace,
+				},
+			}
+
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(milestoneResyncPeriod))
+			Expect(edited).ToNot(BeNil())
+			Expect(edited.GetTitle()).To(Equal("v1.0"))
+
+			reconciled := &issuesv1.GithubMilestone{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Finalizers).To(ContainElement(CloseMilestoneFinalizer))
+			Expect(reconciled.Status.Number).To(Equal(2))
+			Expect(reconciled.Status.OpenIssues).To(Equal(1))
+			Expect(reconciled.Status.ClosedIssues).To(Equal(3))
+			Expect(reconciled.Status.PercentComplete).To(Equal(75))
+			Expect(meta.IsStatusConditionTrue(reconciled.Status.Conditions, "MilestoneSynced")).To(BeTrue())
+		})
+
+		It("plans the milestone in a dry run and lets it go without closing it", func() {
+			ctx := context.Background()
+			milestone := &issuesv1.GithubMilestone{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec:       issuesv1.GithubMilestoneSpec{Repo: "https://github.com/test/test", Title: "v2.0"},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), milestone)
+			Expect(err).To(BeNil())
+
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(mock.GetReposMilestonesByOwnerByRepo, []*github.Milestone{}),
+				mock.WithRequestMatchHandler(
+					mock.PostReposMilestonesByOwnerByRepo,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Fail("a dry run should not create the milestone")
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.PatchReposMilestonesByOwnerByRepoByMilestoneNumber,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Fail("a dry run should not close the milestone")
+					}),
+				),
+			)
+			recorder := record.NewFakeRecorder(10)
+			r := &GithubMilestoneReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient), DryRun: true, Recorder: recorder}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: milestone.Name, Namespace: milestone.Namespace}}
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			reconciled := &issuesv1.GithubMilestone{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "MilestoneSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal("DryRun"))
+			Expect(recorder.Events).To(Receive(Equal(`Normal DryRun create milestone "v2.0"`)))
+
+			reconciled.Status.Number = 4
+			Expect(c.Update(ctx, reconciled)).To(Succeed())
+			Expect(c.Delete(ctx, reconciled)).To(Succeed())
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).ToNot(Succeed())
+			Expect(recorder.Events).To(Receive(Equal("Normal DryRun close milestone #4")))
+		})
+
+		It("does not create milestones in repos that the policies of the namespace do not allow", func() {
+			ctx := context.Background()
+			milestone := &issuesv1.GithubMilestone{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec:       issuesv1.GithubMilestoneSpec{Repo: "https://github.com/test/test", Title: "v2.0"},
+			}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: milestone.Namespace}}
+			policy := &issuesv1.GithubIssuePolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+				Spec:       issuesv1.GithubIssuePolicySpec{Repos: []string{"test/allowed"}},
+			}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), milestone, namespace, policy)
+			Expect(err).To(BeNil())
+
+			//Any GitHub call fails the reconcile
+			MockClient = mock.NewMockedHTTPClient()
+			r := &GithubMilestoneReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: milestone.Name, Namespace: milestone.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(milestoneResyncPeriod))
+
+			reconciled := &issuesv1.GithubMilestone{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			Expect(reconciled.Status.Number).To(BeZero())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "MilestoneSynced")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("PolicyDenied"))
+		})
+
+		It("does not edit or close milestones in a suspended namespace", func() {
+			ctx := context.Background()
+			milestone := &issuesv1.GithubMilestone{
+				ObjectMeta: metav1.ObjectMeta{Name: RandomString(), Namespace: "default"},
+				Spec:       issuesv1.GithubMilestoneSpec{Repo: "https://github.com/test/test", Title: "v2.0"},
+				Status:     issuesv1.GithubMilestoneStatus{Number: 4},
+			}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: milestone.Namespace, Labels: map[string]string{issuesv1.SuspendedNamespaceLabel: "true"}}}
+			c, s, err := CreateFakeClient(GenerateTestIssue(), milestone, namespace)
+			Expect(err).To(BeNil())
+
+			//Any GitHub call fails the reconcile
+			MockClient = mock.NewMockedHTTPClient()
+			r := &GithubMilestoneReconciler{Client: c, Scheme: s, Log: TestLog, GitHubClient: github.NewClient(MockClient)}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: milestone.Name, Namespace: milestone.Namespace}}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(milestoneResyncPeriod))
+
+			reconciled := &issuesv1.GithubMilestone{}
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).To(Succeed())
+			condition := meta.FindStatusCondition(reconciled.Status.Conditions, "Suspended")
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal("NamespaceSuspended"))
+
+			By("letting the milestone go without closing it")
+			Expect(c.Delete(ctx, reconciled)).To(Succeed())
+			_, err = r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Get(ctx, req.NamespacedName, reconciled)).ToNot(Succeed())
+		})
+	})
+
+	Context("When a githubIssue references a githubMilestone", func() {
+		It("sets the milestone number on the issue", func() {
+			ctx := context.Background()
+			milestone := &issuesv1.GithubMilestone{
+				ObjectMeta: metav1.ObjectMeta{Name: "v1", Namespace: "default"},
+				Spec:       issuesv1.GithubMilestoneSpec{Repo: "https://github.com/test/test", Title: "v1.0"},
+				Status:     issuesv1.GithubMilestoneStatus{Number: 2},
+			}
+			testIssue := GenerateTestIssue()
+			testIssue.Spec.MilestoneRef = &issuesv1.MilestoneReference{Name: milestone.Name}
+			c, s, err := CreateFakeClient(testIssue, milestone)
+			Expect(err).To(BeNil())
+
+			var created *github.IssueRequest
+			MockClient = mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposIssuesByOwnerByRepo,
+					[]*github.Issue{},
+					[]*github.Issue{{Number: github.Int(9), Title: github.String(testIssue.Spec.Title), State: github.String("open")}},
+				),
+				mock.WithRequestMatchHandler(
+					mock.PostReposIssuesByOwnerByRepo,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						created = &github.IssueRequest{}
+						Expect(json.NewDecoder(r.Body).Decode(created)).To(Succeed())
+						w.WriteHeader(http.StatusCreated)
+						_, _ = w.Write(mock.MustMarshal(github.Issue{Number: github.Int(9)}))
+					}),
+				),
+			)
+
+			ghClient := github.NewClient(MockClient)
+			r := &GithubIssueReconciler{Client: c,
+				Scheme: s, Log: TestLog, GitHubClient: ghClient}
+
+			_, err = r.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: testIssue.Name, Namespace: testIssue.Namespace},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).ToNot(BeNil())
+			Expect(created.GetMilestone()).To(Equal(2))
+		})
+	})
+})
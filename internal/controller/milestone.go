@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const milestoneIndexKey = "spec.milestoneRef.name"
+
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubmilestones,verbs=get;list;watch
+
+// indexMilestone indexes GithubIssue CRDs by the GithubMilestone they reference
+func indexMilestone(obj client.Object) []string {
+	issue := obj.(*issuesv1.GithubIssue)
+	if issue.Spec.MilestoneRef == nil {
+		return nil
+	}
+	return []string{issue.Spec.MilestoneRef.Name}
+}
+
+// issuesForMilestone maps a GithubMilestone to the GithubIssue CRDs referencing it
+func (r *GithubIssueReconciler) issuesForMilestone(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.issuesMatchingIndex(ctx, obj, milestoneIndexKey)
+}
+
+// ResolveMilestone returns the number of the milestone referenced by the GithubIssue CRD, or nil if it references none
+func (r *GithubIssueReconciler) ResolveMilestone(ctx context.Context, issueObject *issuesv1.GithubIssue) (*int, error) {
+	ref := issueObject.Spec.MilestoneRef
+	if ref == nil {
+		return nil, nil
+	}
+	milestone := &issuesv1.GithubMilestone{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: issueObject.Namespace, Name: ref.Name}, milestone); err != nil {
+		return nil, fmt.Errorf("failed fetching milestone %s: %v", ref.Name, err.Error())
+	}
+	issueRepo, err := repourl.Key(issueObject.Spec.Repo)
+	if err != nil {
+		return nil, err
+	}
+	milestoneRepo, err := repourl.Key(milestone.Spec.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if issueRepo != milestoneRepo {
+		return nil, fmt.Errorf("milestone %s belongs to %s, not %s", ref.Name, milestoneRepo, issueRepo)
+	}
+	if milestone.Status.Number == 0 {
+		return nil, fmt.Errorf("milestone %s is not created yet", ref.Name)
+	}
+	number := milestone.Status.Number
+	return &number, nil
+}
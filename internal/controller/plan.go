@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/plan"
+	"github.com/google/go-github/v56/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// dryRun reports whether the GitHub mutations of the GithubIssue CRD are only planned, manager wide or for this CRD
+func (r *GithubIssueReconciler) dryRun(issueObject *issuesv1.GithubIssue) bool {
+	return r.DryRun || issueObject.Annotations[issuesv1.DryRunAnnotation] == "true"
+}
+
+// RecordPlan records the GitHub mutation planned by a dry run in the status and as an Event.
+// The plan is cleared once dry run is turned off
+func (r *GithubIssueReconciler) RecordPlan(githubIssue *github.Issue, issueObject *issuesv1.GithubIssue, desired *DesiredIssue) bool {
+	if !r.dryRun(issueObject) || desired == nil {
+		if issueObject.Status.Plan == nil {
+			return false
+		}
+		issueObject.Status.Plan = nil
+		return true
+	}
+	p := plan.Compute(githubIssue, plan.Issue(*desired))
+	issuePlan := &issuesv1.IssuePlan{Action: p.Action, Diff: p.Diff}
+	if equality.Semantic.DeepEqual(issuePlan, issueObject.Status.Plan) {
+		return false
+	}
+	issueObject.Status.Plan = issuePlan
+	r.event(issueObject, corev1.EventTypeNormal, "DryRun", p.String())
+	return true
+}
+
+// event records an Event on the GithubIssue CRD when the reconciler has a recorder
+func (r *GithubIssueReconciler) event(issueObject *issuesv1.GithubIssue, eventType string, reason string, message string) {
+	recordEvent(r.Recorder, issueObject, eventType, reason, message)
+}
+
+// recordEvent records an Event on the object when there is a recorder
+func recordEvent(recorder record.EventRecorder, obj runtime.Object, eventType string, reason string, message string) {
+	if recorder != nil {
+		recorder.Event(obj, eventType, reason, message)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/policy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckPolicy sets the PolicyDenied condition while the policies of the namespace do not allow the issue, and
+// removes it once they do
+func (r *GithubIssueReconciler) CheckPolicy(deniedErr error, issueObject *issuesv1.GithubIssue) bool {
+	return setBlocked(issueObject, "PolicyDenied", "PolicyDenied", deniedErr)
+}
+
+// targetDenied checks the policies of the namespace for the objects other than GithubIssue that write to GitHub.
+// A denial is returned apart from the errors evaluating the policies
+func targetDenied(ctx context.Context, c client.Reader, namespace string, repoURL string, labels []string) (*policy.DeniedError, error) {
+	err := policy.CheckTarget(ctx, c, namespace, repoURL, labels)
+	denied := &policy.DeniedError{}
+	if errors.As(err, &denied) {
+		return denied, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/quota"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// quotaReasons are the reasons of the QuotaExceeded condition for each quota scope
+var quotaReasons = map[string]string{
+	quota.ScopeNamespace: "NamespaceQuotaExceeded",
+	quota.ScopeRepo:      "RepoQuotaExceeded",
+	quota.ScopeBreaker:   "CircuitBreakerOpen",
+}
+
+// CheckQuota sets the QuotaExceeded condition while the create of the issue is held by a quota, and removes it once it is not
+func (r *GithubIssueReconciler) CheckQuota(quotaErr error, issueObject *issuesv1.GithubIssue) bool {
+	reason := "QuotaExceeded"
+	exceeded := &quota.ExceededError{}
+	if errors.As(quotaErr, &exceeded) {
+		reason = quotaReasons[exceeded.Scope]
+	}
+	return setBlocked(issueObject, "QuotaExceeded", reason, quotaErr)
+}
+
+// quotaRetry requeues a held create for when the quota allows it again, or for the next sync when that comes first
+// so that a reset of the circuit breaker is picked up
+func (r *GithubIssueReconciler) quotaRetry(quotaErr error, issueObject *issuesv1.GithubIssue) ctrl.Result {
+	result := r.synced(issueObject)
+	exceeded := &quota.ExceededError{}
+	if errors.As(quotaErr, &exceeded) {
+		if untilRetry := time.Until(exceeded.RetryAt); result.RequeueAfter == 0 || untilRetry < result.RequeueAfter {
+			result.RequeueAfter = max(untilRetry, time.Second)
+		}
+	}
+	return result
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=patch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=create
+
+// RecordLinkedPRs records the pull requests cross-referencing the issue, read from its timeline or the batched GraphQL query.
+// Merged pull requests are final, the state of the others is refreshed on every reconcile
+func (r *GithubIssueReconciler) RecordLinkedPRs(ctx context.Context, githubIssue *github.Issue, issueObject *issuesv1.GithubIssue) (bool, error) {
+	if githubIssue == nil {
+		return false, nil
+	}
+	owner, repo, err := repourl.Parse(issueObject.Spec.Repo)
+	if err != nil {
+		return false, err
+	}
+	known := map[string]issuesv1.LinkedPullRequest{}
+	for _, pr := range issueObject.Status.LinkedPRs {
+		known[pr.URL] = pr
+	}
+
+	linked, ok := r.batchedPRs(owner, repo, githubIssue.GetNumber(), known)
+	if !ok {
+		if linked, err = r.timelinePRs(ctx, owner, repo, githubIssue.GetNumber(), known); err != nil {
+			return false, err
+		}
+	}
+
+	if equality.Semantic.DeepEqual(linked, issueObject.Status.LinkedPRs) {
+		return false, nil
+	}
+	issueObject.Status.LinkedPRs = linked
+	return true, nil
+}
+
+// timelinePRs reads the pull requests cross-referencing the issue from its timeline
+func (r *GithubIssueReconciler) timelinePRs(ctx context.Context, owner string, repo string, number int, known map[string]issuesv1.LinkedPullRequest) ([]issuesv1.LinkedPullRequest, error) {
+	var linked []issuesv1.LinkedPullRequest
+	seen := map[string]bool{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		events, response, err := r.GitHubClient.Issues.ListIssueTimeline(ctx, owner, repo, number, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed fetching timeline: %v", err.Error())
+		}
+		for _, event := range events {
+			source := event.GetSource().GetIssue()
+			if event.GetEvent() != "cross-referenced" || !source.IsPullRequest() || seen[source.GetHTMLURL()] {
+				continue
+			}
+			seen[source.GetHTMLURL()] = true
+			pr, err := r.linkedPR(ctx, source, known[source.GetHTMLURL()], owner, repo, number)
+			if err != nil {
+				return nil, err
+			}
+			linked = append(linked, pr)
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+	sort.Slice(linked, func(i, j int) bool { return linked[i].URL < linked[j].URL })
+	return linked, nil
+}
+
+// linkedPR returns the state of a pull request, pull requests may live in another repository than the issue.
+// The timeline does not show links made from the sidebar, so only closing keywords are seen here
+func (r *GithubIssueReconciler) linkedPR(ctx context.Context, source *github.Issue, known issuesv1.LinkedPullRequest, owner string, repo string, number int) (issuesv1.LinkedPullRequest, error) {
+	if known.State == "merged" {
+		return known, nil
+	}
+	prOwner, prRepo, err := repourl.Parse(source.GetHTMLURL())
+	if err != nil {
+		return known, err
+	}
+	pr, _, err := r.GitHubClient.PullRequests.Get(ctx, prOwner, prRepo, source.GetNumber())
+	if err != nil {
+		return known, fmt.Errorf("failed fetching pull request %s: %v", source.GetHTMLURL(), err.Error())
+	}
+	linked := issuesv1.LinkedPullRequest{Number: pr.GetNumber(), URL: source.GetHTMLURL(), State: pr.GetState(),
+		Closes: sameRepo(source.GetHTMLURL(), owner, repo) && closesIssue(pr.GetBody(), owner, repo, number)}
+	if pr.GetMerged() {
+		mergedAt := v1.NewTime(pr.GetMergedAt().Time.Truncate(time.Second))
+		linked.State = "merged"
+		linked.MergedAt = &mergedAt
+	}
+	return linked, nil
+}
+
+// sameRepo reports whether a pull request url is of the repository of the issue, and not of a fork or another repository
+func sameRepo(prURL string, owner string, repo string) bool {
+	key, err := repourl.Key(prURL)
+	return err == nil && key == strings.ToLower(owner+"/"+repo)
+}
+
+// closesIssue reports whether a pull request body closes the issue with one of the keywords GitHub closes issues with
+func closesIssue(body string, owner string, repo string, number int) bool {
+	repoRef := regexp.QuoteMeta(owner + "/" + repo)
+	pattern := fmt.Sprintf(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:(?:%s)?#%d|https://[^\s/]+/%s/issues/%d)\b`,
+		repoRef, number, repoRef, number)
+	return regexp.MustCompile(pattern).MatchString(body)
+}
+
+// Remediate runs the onPullRequestMerged actions once a linked pull request is merged, and reports whether the
+// GithubIssue CRD was deleted by them. Failed actions are retried on the next reconcile, so they must be idempotent:
+// restarts are stamped with the merge time and Jobs are named after the pull request
+func (r *GithubIssueReconciler) Remediate(ctx context.Context, issueObject *issuesv1.GithubIssue) (changed bool, deleted bool, err error) {
+	if len(issueObject.Spec.OnPullRequestMerged) == 0 || issueObject.Status.RemediatedAt != nil ||
+		r.dryRun(issueObject) || r.suspended(ctx, issueObject) {
+		return false, false, nil
+	}
+	var merged *issuesv1.LinkedPullRequest
+	for i, pr := range issueObject.Status.LinkedPRs {
+		//Pull requests that only mention the issue, from forks or other repositories, do not remediate it
+		if pr.State == "merged" && pr.Closes && (merged == nil || pr.MergedAt.Before(merged.MergedAt)) {
+			merged = &issueObject.Status.LinkedPRs[i]
+		}
+	}
+	if merged == nil {
+		return false, false, nil
+	}
+
+	deleteIssue := false
+	for _, action := range issueObject.Spec.OnPullRequestMerged {
+		switch {
+		case action.RolloutRestart != nil:
+			err = r.rolloutRestart(ctx, issueObject.Namespace, action.RolloutRestart, merged)
+		case action.CreateJob != nil:
+			err = r.createJob(ctx, issueObject, action.CreateJob, merged)
+		case action.DeleteIssue:
+			deleteIssue = true
+		}
+		if err != nil {
+			condition := v1.Condition{Type: "Remediated", Status: v1.ConditionFalse, Reason: "ActionFailed", Message: err.Error()}
+			if meta.IsStatusConditionPresentAndEqual(issueObject.Status.Conditions, condition.Type, condition.Status) &&
+				meta.FindStatusCondition(issueObject.Status.Conditions, condition.Type).Message == condition.Message {
+				return false, false, err
+			}
+			meta.SetStatusCondition(&issueObject.Status.Conditions, condition)
+			return true, false, err
+		}
+	}
+	if deleteIssue {
+		r.Log.Info("pull request merged, deleting issue")
+		if err := r.Delete(ctx, issueObject); err != nil {
+			return false, false, fmt.Errorf("failed deleting issue: %v", err.Error())
+		}
+		return false, true, nil
+	}
+	now := v1.Now()
+	issueObject.Status.RemediatedAt = &now
+	meta.SetStatusCondition(&issueObject.Status.Conditions, v1.Condition{
+		Type:    "Remediated",
+		Status:  v1.ConditionTrue,
+		Reason:  "ActionsSucceeded",
+		Message: fmt.Sprintf("Ran the actions of %s", merged.URL),
+	})
+	return true, false, nil
+}
+
+func (r *GithubIssueReconciler) rolloutRestart(ctx context.Context, namespace string, action *issuesv1.RolloutRestartAction, pr *issuesv1.LinkedPullRequest) error {
+	workload := &unstructured.Unstructured{}
+	workload.SetAPIVersion("apps/v1")
+	workload.SetKind(action.Kind)
+	workload.SetNamespace(namespace)
+	workload.SetName(action.Name)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{
+			"annotations": map[string]string{restartedAtAnnotation: pr.MergedAt.UTC().Format(time.RFC3339)},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, workload, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed restarting %s %s: %v", action.Kind, action.Name, err.Error())
+	}
+	r.Log.Info(fmt.Sprintf("pull request merged, restarted %s %s", action.Kind, action.Name))
+	return nil
+}
+
+func (r *GithubIssueReconciler) createJob(ctx context.Context, issueObject *issuesv1.GithubIssue, action *issuesv1.CreateJobAction, pr *issuesv1.LinkedPullRequest) error {
+	cronJob := &batchv1.CronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: issueObject.Namespace, Name: action.FromCronJob}, cronJob); err != nil {
+		return fmt.Errorf("failed fetching cronjob %s: %v", action.FromCronJob, err.Error())
+	}
+	template := cronJob.Spec.JobTemplate
+	job := &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        remediationJobName(issueObject.Name, pr.Number),
+			Namespace:   issueObject.Namespace,
+			Labels:      template.Labels,
+			Annotations: map[string]string{"cronjob.kubernetes.io/instantiate": "manual"},
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	for key, value := range template.Annotations {
+		job.Annotations[key] = value
+	}
+	if err := controllerutil.SetControllerReference(cronJob, job, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("failed creating job from cronjob %s: %v", action.FromCronJob, err.Error())
+	}
+	r.Log.Info(fmt.Sprintf("pull request merged, created job %s", job.Name))
+	return nil
+}
+
+// remediationJobName names the Job created for a merged pull request. Job names end up in pod labels,
+// so they are kept within 63 characters
+func remediationJobName(issueName string, number int) string {
+	suffix := fmt.Sprintf("-pr-%d", number)
+	if len(issueName)+len(suffix) > 63 {
+		issueName = issueName[:63-len(suffix)]
+	}
+	return issueName + suffix
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// syncJitter is the fraction of the sync interval added at random to each requeue,
+// so that issues created together do not all poll GitHub together
+const syncJitter = 0.1
+
+// syncInterval returns how long to wait before refreshing the GithubIssue CRD from GitHub. The interval of the issue,
+// or the manager default, is raised to MinSyncInterval, it is zero and never refreshed only when both are zero
+func (r *GithubIssueReconciler) syncInterval(issueObject *issuesv1.GithubIssue) time.Duration {
+	interval := r.SyncInterval
+	if issueObject.Spec.SyncInterval != nil {
+		interval = issueObject.Spec.SyncInterval.Duration
+	}
+	if interval < r.MinSyncInterval {
+		interval = r.MinSyncInterval
+	}
+	return interval
+}
+
+// synced requeues the GithubIssue CRD after its jittered sync interval
+func (r *GithubIssueReconciler) synced(issueObject *issuesv1.GithubIssue) ctrl.Result {
+	interval := r.syncInterval(issueObject)
+	if interval <= 0 {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: wait.Jitter(interval, syncJitter)}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ScreenContent redacts the title and body of the issue matching the safety rules, or returns a
+// safety.BlockedError when they must not be sent
+func (r *GithubIssueReconciler) ScreenContent(issueObject *issuesv1.GithubIssue, desired *DesiredIssue) error {
+	rules, err := r.Safety.Screen(&desired.Title, &desired.Body)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		r.Log.Info(fmt.Sprintf("redacted content matching %s", strings.Join(rules, ", ")))
+		r.event(issueObject, corev1.EventTypeWarning, "ContentRedacted", fmt.Sprintf("Redacted content matching the safety rules %s", strings.Join(rules, ", ")))
+	}
+	return nil
+}
+
+// CheckContent sets the ContentBlocked condition while the content of the issue is blocked, and removes it once it is not
+func (r *GithubIssueReconciler) CheckContent(blockErr error, issueObject *issuesv1.GithubIssue) bool {
+	return setBlocked(issueObject, "ContentBlocked", "SafetyRuleMatched", blockErr)
+}
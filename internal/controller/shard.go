@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// repoBusyRetryPeriod is how long a reconcile waits for another one of the same repository to finish.
+// Reconciles of a repository are serialized to stay clear of the GitHub secondary rate limits
+const repoBusyRetryPeriod = time.Second
+
+// repoLocks serializes the reconciles of each repository, the zero value is ready to use
+type repoLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// tryLock locks the repository unless another reconcile holds it
+func (l *repoLocks) tryLock(repo string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[repo] {
+		return false
+	}
+	if l.held == nil {
+		l.held = map[string]bool{}
+	}
+	l.held[repo] = true
+	return true
+}
+
+func (l *repoLocks) unlock(repo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, repo)
+}
+
+// Shard selects the GithubIssue CRDs reconciled by this manager when a large fleet is spread across several
+// managers. Issues are assigned by a hash of their repository, so that each repository is reconciled by one manager
+// and its reconciles stay serialized.
+// Issues carrying Label are assigned by a hash of the label value instead, which gives up that serialization: issues
+// of one repository with different label values can land on different managers, that then reconcile it concurrently
+type Shard struct {
+	//Index of this manager, from 0 to Count-1
+	Index int
+	//Count of managers, sharding is off when it is 1 or less
+	Count int
+	//Label whose value is hashed instead of the repository when set on an issue. Repositories whose issues are split
+	//across shards by it are no longer serialized, the reconciler warns about them
+	Label string
+}
+
+// Owns reports whether the GithubIssue CRD belongs to this shard
+func (s Shard) Owns(issueObject *issuesv1.GithubIssue) bool {
+	return s.Count <= 1 || s.shardOf(issueObject) == s.Index
+}
+
+// shardOf returns the index of the shard the GithubIssue CRD belongs to
+func (s Shard) shardOf(issueObject *issuesv1.GithubIssue) int {
+	key, ok := issueObject.Labels[s.Label]
+	if s.Label == "" || !ok {
+		var err error
+		if key, err = repourl.Key(issueObject.Spec.Repo); err != nil {
+			key = client.ObjectKeyFromObject(issueObject).String()
+		}
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(s.Count))
+}
+
+// warnSplitRepo warns once per repository when the shard label spreads its GithubIssue CRDs across shards
+func (r *GithubIssueReconciler) warnSplitRepo(ctx context.Context, issueObject *issuesv1.GithubIssue, repoKey string) {
+	if r.Shard.Count <= 1 || r.Shard.Label == "" {
+		return
+	}
+	if _, ok := issueObject.Labels[r.Shard.Label]; !ok {
+		return
+	}
+	if _, warned := r.splitRepos.Load(repoKey); warned {
+		return
+	}
+	issues := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, issues, client.MatchingFields{repoIndexKey: repoKey}); err != nil {
+		r.Log.Error("failed listing issues of the repository", zap.Error(err))
+		return
+	}
+	for i := range issues.Items {
+		if shard := r.Shard.shardOf(&issues.Items[i]); shard != r.Shard.Index {
+			r.splitRepos.Store(repoKey, true)
+			r.Log.Warn(fmt.Sprintf("issues of %s are split across shards %d and %d by the %s label, reconciles of the repository are not serialized",
+				repoKey, r.Shard.Index, shard, r.Shard.Label))
+			return
+		}
+	}
+}
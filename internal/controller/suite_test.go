@@ -18,9 +18,14 @@ package controller
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 
 	issuesv1 "dvir.io/githubissue/api/v1"
+	issuesv2 "dvir.io/githubissue/api/v2"
+	webhookv1 "dvir.io/githubissue/internal/webhook/v1"
+	webhookv2 "dvir.io/githubissue/internal/webhook/v2"
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"net/http"
@@ -45,6 +50,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -72,21 +78,26 @@ var _ = BeforeSuite(func() {
 	testLog := zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true))
 	logf.SetLogger(testLog)
 	By("bootstrapping test environment")
+	// v2 is the storage version, so the conversion webhook has to be served for the v1 objects used by the tests
+	err := issuesv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = issuesv2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
 		BinaryAssetsDirectory: filepath.Join("..", "..", "bin", "k8s",
 			fmt.Sprintf("1.28.0-%s-%s", runtime.GOOS, runtime.GOARCH)),
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
 	}
 
-	var err error
 	cfg, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
-	err = issuesv1.AddToScheme(scheme.Scheme)
-	Expect(err).NotTo(HaveOccurred())
-
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
@@ -97,11 +108,19 @@ var _ = BeforeSuite(func() {
 	}
 
 	Expect(k8sClient.Create(ctx, newNamespace)).Should(Succeed())
+	webhookInstallOptions := &testEnv.WebhookInstallOptions
 	k8sManager, err = ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookInstallOptions.LocalServingHost,
+			Port:    webhookInstallOptions.LocalServingPort,
+			CertDir: webhookInstallOptions.LocalServingCertDir,
+		}),
 	})
 
 	Expect(err).ToNot(HaveOccurred())
+	Expect(webhookv1.SetupGithubIssueWebhookWithManager(k8sManager, nil, "")).To(Succeed())
+	Expect(webhookv2.SetupGithubIssueWebhookWithManager(k8sManager, "github.com")).To(Succeed())
 	encoderConfig := ecszap.NewDefaultEncoderConfig()
 	core := ecszap.NewCore(encoderConfig, os.Stdout, uberzap.DebugLevel)
 	TestLog = uberzap.New(core, uberzap.AddCaller())
@@ -124,4 +143,15 @@ var _ = BeforeSuite(func() {
 		Expect(err).NotTo(HaveOccurred())
 	}()
 
+	// wait for the webhook server to get ready
+	dialer := &net.Dialer{Timeout: time.Second}
+	addrPort := fmt.Sprintf("%s:%d", webhookInstallOptions.LocalServingHost, webhookInstallOptions.LocalServingPort)
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addrPort, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
+
 })
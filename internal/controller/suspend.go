@@ -0,0 +1,116 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// suspension returns why writes to the GitHub issue of the GithubIssue CRD are suspended, or an empty reason
+func (r *GithubIssueReconciler) suspension(ctx context.Context, issueObject *issuesv1.GithubIssue) (reason string, message string, err error) {
+	return issueSuspension(ctx, r.Client, issueObject)
+}
+
+// issueSuspension returns why writes to the GitHub issue of the GithubIssue CRD are suspended, or an empty reason.
+// It is shared with the reconcilers of the objects writing to the same issue
+func issueSuspension(ctx context.Context, c client.Reader, issueObject *issuesv1.GithubIssue) (reason string, message string, err error) {
+	if issueObject.Spec.Suspend {
+		return "SpecSuspended", "spec.suspend is set", nil
+	}
+	if issueObject.Annotations[issuesv1.PausedAnnotation] == "true" {
+		return "Paused", fmt.Sprintf("the %s annotation is set", issuesv1.PausedAnnotation), nil
+	}
+	return namespaceSuspension(ctx, c, issueObject.Namespace)
+}
+
+// namespaceSuspension returns why writes from the namespace are suspended, or an empty reason
+func namespaceSuspension(ctx context.Context, c client.Reader, namespaceName string) (reason string, message string, err error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("failed fetching namespace %s: %v", namespaceName, err.Error())
+	}
+	if namespace.Labels[issuesv1.SuspendedNamespaceLabel] == "true" {
+		return "NamespaceSuspended", fmt.Sprintf("namespace %s has the %s label", namespace.Name, issuesv1.SuspendedNamespaceLabel), nil
+	}
+	return "", "", nil
+}
+
+// suspended reports whether writes to the GitHub issue are suspended, failing closed
+func (r *GithubIssueReconciler) suspended(ctx context.Context, issueObject *issuesv1.GithubIssue) bool {
+	reason, _, err := r.suspension(ctx, issueObject)
+	return reason != "" || err != nil
+}
+
+// CheckSuspended sets the Suspended condition while writes to the GitHub issue are suspended, and removes it once resumed
+func (r *GithubIssueReconciler) CheckSuspended(ctx context.Context, issueObject *issuesv1.GithubIssue) bool {
+	reason, message, err := r.suspension(ctx, issueObject)
+	if err != nil {
+		return false
+	}
+	return setSuspended(&issueObject.Status.Conditions, reason, message)
+}
+
+// setSuspended sets the Suspended condition while there is a reason, and removes it once resumed.
+// It reports whether the conditions changed
+func setSuspended(conditions *[]v1.Condition, reason string, message string) bool {
+	if reason == "" {
+		if meta.FindStatusCondition(*conditions, "Suspended") == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(conditions, "Suspended")
+		return true
+	}
+	condition := meta.FindStatusCondition(*conditions, "Suspended")
+	if condition != nil && condition.Reason == reason {
+		return false
+	}
+	meta.SetStatusCondition(conditions, v1.Condition{
+		Type:    "Suspended",
+		Status:  v1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	return true
+}
+
+// issuesForNamespace maps a Namespace to the GithubIssue CRDs in it, so that suspending the namespace takes effect
+func (r *GithubIssueReconciler) issuesForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	issues := &issuesv1.GithubIssueList{}
+	if err := r.List(ctx, issues, client.InNamespace(obj.GetName())); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(issues.Items))
+	for _, issue := range issues.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&issue)})
+	}
+	return requests
+}
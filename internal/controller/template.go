@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/render"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// templateRetryPeriod is how long to wait before rendering a broken template again.
+// Template inputs are arbitrary objects that are not watched, so changes to them are picked up by polling
+const templateRetryPeriod = time.Minute
+
+//+kubebuilder:rbac:groups="",resources=pods;services,verbs=get
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get
+//+kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get
+
+// ResolveIssue resolves the description source and milestone, and renders the templates of the GithubIssue CRD.
+// Rendering failures are returned as *render.Error
+func (r *GithubIssueReconciler) ResolveIssue(ctx context.Context, issueObject *issuesv1.GithubIssue) (*DesiredIssue, error) {
+	description, err := r.ResolveDescription(ctx, issueObject)
+	if err != nil {
+		return nil, err
+	}
+	milestone, err := r.ResolveMilestone(ctx, issueObject)
+	if err != nil {
+		return nil, err
+	}
+	desired := &DesiredIssue{
+		Title:     issueObject.Spec.Title,
+		Body:      description,
+		Labels:    issueObject.Spec.Labels,
+		Assignees: issueObject.Spec.Assignees,
+		Milestone: milestone,
+
+		State:       issueObject.Spec.State,
+		StateReason: issueObject.Spec.StateReason,
+	}
+	if len(issueObject.Spec.TemplateInputs) == 0 {
+		return desired, nil
+	}
+	data, err := r.templateData(ctx, issueObject)
+	if err != nil {
+		return nil, err
+	}
+	if desired.Title, err = render.Render("title", desired.Title, data); err != nil {
+		return nil, err
+	}
+	if desired.Body, err = render.Render("description", desired.Body, data); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
+
+// templateData fetches the template inputs of the GithubIssue CRD, keyed by their name.
+// The GithubIssue itself is exposed as Issue
+func (r *GithubIssueReconciler) templateData(ctx context.Context, issueObject *issuesv1.GithubIssue) (map[string]interface{}, error) {
+	issueData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(issueObject)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting issue: %v", err.Error())
+	}
+	data := map[string]interface{}{"Issue": issueData}
+	for _, input := range issueObject.Spec.TemplateInputs {
+		inputName := fmt.Sprintf("templateInputs[%s]", input.Name)
+		groupVersion, err := schema.ParseGroupVersion(input.ObjectRef.APIVersion)
+		if err != nil {
+			return nil, &render.Error{Name: inputName, Err: err}
+		}
+		gvk := groupVersion.WithKind(input.ObjectRef.Kind)
+		//The manager reads Secrets for description sources, they must not leak into issues through templates
+		if gvk.Group == "" && gvk.Kind == "Secret" {
+			return nil, &render.Error{Name: inputName, Err: fmt.Errorf("secrets cannot be template inputs")}
+		}
+		mapping, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, &render.Error{Name: inputName, Err: err}
+		}
+		key := types.NamespacedName{Name: input.ObjectRef.Name}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			key.Namespace = issueObject.Namespace
+		}
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(gvk)
+		if err := r.Get(ctx, key, object); err != nil {
+			return nil, &render.Error{Name: inputName, Err: err}
+		}
+		data[input.Name] = object.Object
+	}
+	return data, nil
+}
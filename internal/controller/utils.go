@@ -8,15 +8,28 @@ import (
 
 	issuesv1 "dvir.io/githubissue/api/v1"
 	"github.com/google/go-github/v56/github"
+	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// DesiredIssue is the issue a GithubIssue CRD resolves to once its description source and templates are applied
+type DesiredIssue struct {
+	Title     string
+	Body      string
+	Labels    []string
+	Assignees []string
+	Milestone *int
+	//State is empty when the state of the issue is not managed
+	State       string
+	StateReason string
+}
+
 // Checks if GithubIssue CRD has an issue in the repo
-func searchForIssue(issue *issuesv1.GithubIssue, gitHubIssues []*github.Issue) *github.Issue {
+func searchForIssue(title string, gitHubIssues []*github.Issue) *github.Issue {
 	for _, ghIssue := range gitHubIssues {
-		if strings.EqualFold(*ghIssue.Title, issue.Spec.Title) {
+		if strings.EqualFold(*ghIssue.Title, title) {
 
 			return ghIssue
 		}
@@ -24,6 +37,22 @@ func searchForIssue(issue *issuesv1.GithubIssue, gitHubIssues []*github.Issue) *
 	return nil
 }
 
+// searchTitle returns the title of the issue as last rendered, for when the templates can no longer be resolved
+func searchTitle(issue *issuesv1.GithubIssue) string {
+	if len(issue.Spec.TemplateInputs) > 0 && issue.Status.RenderedTitle != "" {
+		return issue.Status.RenderedTitle
+	}
+	return issue.Spec.Title
+}
+
+// boundIssueNumber returns the issue number the GithubIssue CRD is bound to, or 0 if it is not bound yet
+func boundIssueNumber(issue *issuesv1.GithubIssue) int {
+	if issue.Spec.Number != 0 {
+		return issue.Spec.Number
+	}
+	return issue.Status.Number
+}
+
 // AddFinalizer adds finalizer to GithubIssue CRD
 func (r *GithubIssueReconciler) AddFinalizer(ctx context.Context, issue *issuesv1.GithubIssue) (err error) {
 
@@ -60,25 +89,132 @@ func (r *GithubIssueReconciler) DeleteFinalizer(ctx context.Context, issue *issu
 }
 
 // UpdateIssueStatus updates the status of the GithubIssue CRD
-func (r *GithubIssueReconciler) UpdateIssueStatus(ctx context.Context, issue *issuesv1.GithubIssue, githubIssue *github.Issue) error {
+func (r *GithubIssueReconciler) UpdateIssueStatus(ctx context.Context, issue *issuesv1.GithubIssue, githubIssue *github.Issue, desired *DesiredIssue) error {
+	LinkedChange, err := r.RecordLinkedPRs(ctx, githubIssue, issue)
+	if err != nil {
+		r.Log.Error("failed recording linked pull requests", zap.Error(err))
+	}
 	PRChange := r.CheckForPr(githubIssue, issue)
 	OpenChange := r.CheckIfOpen(githubIssue, issue)
 
-	if OpenChange || PRChange {
-		r.Log.Info("editing Issue status")
-		err := r.Client.Status().Update(ctx, issue)
-		if err != nil {
-			//Necessary for tests
-			if err := r.Client.Update(ctx, issue); err != nil {
-				return fmt.Errorf("unable to update status of CR: %v", err.Error())
-			}
-		}
-		r.Log.Info("updated Issue status")
+	BindingChange := r.RecordBinding(githubIssue, issue)
+	RenderChange := r.RecordRendered(desired, issue)
+	TemplateChange := r.CheckTemplate(nil, issue)
+	ActivityChange := r.RecordActivity(githubIssue, issue)
+	CommentsChange, err := r.MirrorComments(ctx, githubIssue, issue)
+	if err != nil {
+		r.Log.Error("failed mirroring comments", zap.Error(err))
+	}
+
+	PlanChange := r.RecordPlan(githubIssue, issue, desired)
+	SuspendedChange := r.CheckSuspended(ctx, issue)
+	RequestChange := r.RecordReconcileRequest(issue)
+	ContentChange := r.CheckContent(nil, issue)
+	PolicyChange := r.CheckPolicy(nil, issue)
+	QuotaChange := r.CheckQuota(nil, issue)
+	RemediationChange, deleted, err := r.Remediate(ctx, issue)
+	if err != nil {
+		r.Log.Error("failed running onPullRequestMerged actions", zap.Error(err))
+	}
+	if deleted {
 		return nil
+	}
+
+	if OpenChange || PRChange || LinkedChange || BindingChange || RenderChange || TemplateChange || ActivityChange || CommentsChange || RemediationChange || PlanChange || SuspendedChange || RequestChange || ContentChange || PolicyChange || QuotaChange {
+		return r.updateStatus(ctx, issue)
+	}
+	return nil
+
+}
 
+// updateStatus writes the status of the GithubIssue CRD
+func (r *GithubIssueReconciler) updateStatus(ctx context.Context, issue *issuesv1.GithubIssue) error {
+	r.Log.Info("editing Issue status")
+	err := r.Client.Status().Update(ctx, issue)
+	if err != nil {
+		//Necessary for tests
+		if err := r.Client.Update(ctx, issue); err != nil {
+			return fmt.Errorf("unable to update status of CR: %v", err.Error())
+		}
 	}
+	r.Log.Info("updated Issue status")
 	return nil
+}
 
+// RecordRendered records the rendered title and description in the status of templated GithubIssue CRDs
+func (r *GithubIssueReconciler) RecordRendered(desired *DesiredIssue, issueObject *issuesv1.GithubIssue) bool {
+	title, description := "", ""
+	if desired != nil && len(issueObject.Spec.TemplateInputs) > 0 {
+		title, description = desired.Title, desired.Body
+	}
+	if issueObject.Status.RenderedTitle == title && issueObject.Status.RenderedDescription == description {
+		return false
+	}
+	issueObject.Status.RenderedTitle = title
+	issueObject.Status.RenderedDescription = description
+	return true
+}
+
+// CheckTemplate sets the TemplateRendered condition of templated GithubIssue CRDs from the rendering error, if any
+func (r *GithubIssueReconciler) CheckTemplate(renderErr error, issueObject *issuesv1.GithubIssue) bool {
+	if len(issueObject.Spec.TemplateInputs) == 0 {
+		if meta.FindStatusCondition(issueObject.Status.Conditions, "TemplateRendered") == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&issueObject.Status.Conditions, "TemplateRendered")
+		return true
+	}
+	condition := v1.Condition{Type: "TemplateRendered", Status: v1.ConditionTrue, Reason: "TemplateRendered", Message: "Title and description rendered"}
+	if renderErr != nil {
+		condition = v1.Condition{Type: "TemplateRendered", Status: v1.ConditionFalse, Reason: "RenderFailed", Message: renderErr.Error()}
+	}
+	existing := meta.FindStatusCondition(issueObject.Status.Conditions, "TemplateRendered")
+	if existing != nil && existing.Status == condition.Status && existing.Message == condition.Message {
+		return false
+	}
+	meta.SetStatusCondition(&issueObject.Status.Conditions, condition)
+	return true
+}
+
+// setBlocked sets a condition of the given type to True while err is not nil, and removes it once it is nil.
+// It returns whether the conditions changed
+func setBlocked(issueObject *issuesv1.GithubIssue, conditionType string, reason string, err error) bool {
+	existing := meta.FindStatusCondition(issueObject.Status.Conditions, conditionType)
+	if err == nil {
+		if existing == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&issueObject.Status.Conditions, conditionType)
+		return true
+	}
+	if existing != nil && existing.Status == v1.ConditionTrue && existing.Reason == reason && existing.Message == err.Error() {
+		return false
+	}
+	meta.SetStatusCondition(&issueObject.Status.Conditions, v1.Condition{Type: conditionType, Status: v1.ConditionTrue, Reason: reason, Message: err.Error()})
+	return true
+}
+
+// RecordBinding records the number and url of the GitHub issue in the status of the GithubIssue CRD
+func (r *GithubIssueReconciler) RecordBinding(githubIssue *github.Issue, issueObject *issuesv1.GithubIssue) bool {
+	if githubIssue == nil {
+		return false
+	}
+	if issueObject.Status.Number == githubIssue.GetNumber() && issueObject.Status.URL == githubIssue.GetHTMLURL() {
+		return false
+	}
+	issueObject.Status.Number = githubIssue.GetNumber()
+	issueObject.Status.URL = githubIssue.GetHTMLURL()
+	return true
+}
+
+// RecordReconcileRequest records the reconcile requested through the issues.dvir.io/reconcile-requested-at annotation as handled
+func (r *GithubIssueReconciler) RecordReconcileRequest(issueObject *issuesv1.GithubIssue) bool {
+	requestedAt := issueObject.Annotations[issuesv1.ReconcileRequestedAnnotation]
+	if issueObject.Status.LastHandledReconcileAt == requestedAt {
+		return false
+	}
+	issueObject.Status.LastHandledReconcileAt = requestedAt
+	return true
 }
 
 // CheckIfOpen check if issue is open
@@ -97,10 +233,17 @@ func (r *GithubIssueReconciler) CheckIfOpen(githubIssue *github.Issue, issueObje
 // CheckForPr check if issue has an open PR
 func (r *GithubIssueReconciler) CheckForPr(githubIssue *github.Issue, issueObject *issuesv1.GithubIssue) bool {
 	condition := &v1.Condition{Type: "IssueHasPR", Status: v1.ConditionFalse, Reason: "IssueHasnopr", Message: "Issue has no pr"}
-	if githubIssue.GetPullRequestLinks() != nil {
+	if githubIssue.GetPullRequestLinks() != nil || len(issueObject.Status.LinkedPRs) > 0 {
 		condition = &v1.Condition{Type: "IssueHasPR", Status: v1.ConditionTrue, Reason: "IssueHasPR", Message: "Issue Has an open PR"}
 	}
-	if !meta.IsStatusConditionPresentAndEqual(issueObject.Status.Conditions, "IssueHasPR", condition.Status) {
+	for _, pr := range issueObject.Status.LinkedPRs {
+		if pr.State == "merged" {
+			condition = &v1.Condition{Type: "IssueHasPR", Status: v1.ConditionTrue, Reason: "PRMerged", Message: fmt.Sprintf("PR %s was merged", pr.URL)}
+			break
+		}
+	}
+	if !meta.IsStatusConditionPresentAndEqual(issueObject.Status.Conditions, "IssueHasPR", condition.Status) ||
+		meta.FindStatusCondition(issueObject.Status.Conditions, "IssueHasPR").Reason != condition.Reason {
 		meta.SetStatusCondition(&issueObject.Status.Conditions, *condition)
 		return true
 	}
@@ -139,8 +282,15 @@ func (r *GithubIssueReconciler) CloseIssue(ctx context.Context, owner string, re
 }
 
 // CreateIssue add an issue to the repo
-func (r *GithubIssueReconciler) CreateIssue(ctx context.Context, owner string, repo string, issueObject *issuesv1.GithubIssue) error {
-	newIssue := &github.IssueRequest{Title: &issueObject.Spec.Title, Body: &issueObject.Spec.Description}
+func (r *GithubIssueReconciler) CreateIssue(ctx context.Context, owner string, repo string, desired *DesiredIssue) error {
+	newIssue := &github.IssueRequest{Title: &desired.Title, Body: &desired.Body}
+	if len(desired.Labels) > 0 {
+		newIssue.Labels = &desired.Labels
+	}
+	if len(desired.Assignees) > 0 {
+		newIssue.Assignees = &desired.Assignees
+	}
+	newIssue.Milestone = desired.Milestone
 	_, response, err := r.GitHubClient.Issues.Create(ctx, owner, repo, newIssue)
 	if err != nil {
 		if response != nil {
@@ -157,8 +307,21 @@ func (r *GithubIssueReconciler) CreateIssue(ctx context.Context, owner string, r
 }
 
 // EditIssue change the description of an existing issue in the repo
-func (r *GithubIssueReconciler) EditIssue(ctx context.Context, owner string, repo string, issueObject *issuesv1.GithubIssue, issueNumber int) error {
-	editIssueRequest := &github.IssueRequest{Body: &issueObject.Spec.Description}
+func (r *GithubIssueReconciler) EditIssue(ctx context.Context, owner string, repo string, desired *DesiredIssue, issueNumber int) error {
+	editIssueRequest := &github.IssueRequest{Title: &desired.Title, Body: &desired.Body}
+	if len(desired.Labels) > 0 {
+		editIssueRequest.Labels = &desired.Labels
+	}
+	if len(desired.Assignees) > 0 {
+		editIssueRequest.Assignees = &desired.Assignees
+	}
+	editIssueRequest.Milestone = desired.Milestone
+	if desired.State != "" {
+		editIssueRequest.State = &desired.State
+	}
+	if desired.State == "closed" && desired.StateReason != "" {
+		editIssueRequest.StateReason = &desired.StateReason
+	}
 	_, response, err := r.GitHubClient.Issues.Edit(ctx, owner, repo, issueNumber, editIssueRequest)
 	if err != nil {
 		if response != nil {
@@ -168,13 +331,30 @@ func (r *GithubIssueReconciler) EditIssue(ctx context.Context, owner string, rep
 		return fmt.Errorf("failed editing issue: status %s: %v", response.Status, err.Error())
 
 	}
+	if r.Batcher != nil {
+		r.Batcher.Forget(owner, repo, issueNumber)
+	}
 	return nil
 }
 
-func (r *GithubIssueReconciler) FindIssue(ctx context.Context, owner string, repo string, issue *issuesv1.GithubIssue) (*github.Issue, error) {
+// FindIssue gets the issue bound to the GithubIssue CRD, falling back to searching the repo by title
+func (r *GithubIssueReconciler) FindIssue(ctx context.Context, owner string, repo string, issue *issuesv1.GithubIssue, title string) (*github.Issue, error) {
+	if number := boundIssueNumber(issue); number != 0 {
+		if gitHubIssue := r.fetchBatched(ctx, owner, repo, issue, number); gitHubIssue != nil {
+			return gitHubIssue, nil
+		}
+		gitHubIssue, response, err := r.GitHubClient.Issues.Get(ctx, owner, repo, number)
+		if err != nil {
+			if response != nil {
+				return nil, fmt.Errorf("failed fetching issue #%d: status %s: %v", number, response.Status, err.Error())
+			}
+			return nil, fmt.Errorf("failed fetching issue #%d: %v", number, err.Error())
+		}
+		return gitHubIssue, nil
+	}
 	allIssues, err := r.fetchAllIssues(ctx, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("falied fetching error: %v", err.Error())
 	}
-	return searchForIssue(issue, allIssues), nil
+	return searchForIssue(title, allIssues), nil
 }
@@ -0,0 +1,539 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// WorkloadReconciler files a GithubIssue for Pods, Jobs and Deployments annotated with ReportFailuresAnnotation
+// when they fail, and closes it when they recover. Issues are filed per owning workload, so the pods of a
+// Deployment share a single issue
+type WorkloadReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    *zap.Logger
+	//Clientset reads the logs of the failing pods, which the controller-runtime client cannot
+	Clientset kubernetes.Interface
+	//LogLines is the number of log lines of each failing container included in the issue
+	LogLines int64
+}
+
+const (
+	defaultLogLines = 50
+	// maxReportedPods bounds the number of pods whose logs are included, GitHub rejects bodies over 64KB
+	maxReportedPods = 3
+	maxLogBytes     = 8 * 1024
+	maxEvents       = 10
+)
+
+// workloadFailure is why a workload is failing
+type workloadFailure struct {
+	Reason  string
+	Message string
+	//Pods whose logs are included in the issue
+	Pods []corev1.Pod
+}
+
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=list
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+// ReconcileDeployment reports a Deployment whose rollout stalled or whose pods are crash looping
+func (r *WorkloadReconciler) ReconcileDeployment(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, req.NamespacedName, deployment); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !reportsFailures(deployment) {
+		return ctrl.Result{}, nil
+	}
+	pods, err := r.selectedPods(ctx, deployment.Namespace, deployment.Spec.Selector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var failure *workloadFailure
+	if crashing := crashLoopingPods(pods); len(crashing) > 0 {
+		failure = &workloadFailure{
+			Reason:  "CrashLoopBackOff",
+			Message: fmt.Sprintf("%d pods are crash looping", len(crashing)),
+			Pods:    crashing,
+		}
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded" {
+			failure = &workloadFailure{Reason: condition.Reason, Message: condition.Message, Pods: unreadyPods(pods)}
+		}
+	}
+	owner, err := r.referenceTo(deployment)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.report(ctx, deployment.Namespace, owner, deployment.Annotations[issuesv1.ReportFailuresAnnotation], failure)
+}
+
+// ReconcileJob reports a failed Job, or a Job whose pods are crash looping. Jobs of a CronJob are reported as the CronJob
+func (r *WorkloadReconciler) ReconcileJob(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, req.NamespacedName, job); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !reportsFailures(job) {
+		return ctrl.Result{}, nil
+	}
+	pods, err := r.selectedPods(ctx, job.Namespace, job.Spec.Selector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var failure *workloadFailure
+	if crashing := crashLoopingPods(pods); len(crashing) > 0 {
+		failure = &workloadFailure{
+			Reason:  "CrashLoopBackOff",
+			Message: fmt.Sprintf("%d pods are crash looping", len(crashing)),
+			Pods:    crashing,
+		}
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			failure = &workloadFailure{Reason: condition.Reason, Message: condition.Message, Pods: failedPods(pods)}
+		}
+	}
+	owner, _, err := r.ownerOf(ctx, job)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if owner.UID != job.UID {
+		//The runs of a CronJob share its issue, only the latest run opens it and only once it finished does it close it
+		latest, err := r.latestRun(ctx, job.Namespace, owner.UID)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if latest != nil && latest.UID != job.UID {
+			return ctrl.Result{}, nil
+		}
+		if failure == nil && !jobFinished(job) {
+			return ctrl.Result{}, nil
+		}
+	}
+	return ctrl.Result{}, r.report(ctx, job.Namespace, owner, job.Annotations[issuesv1.ReportFailuresAnnotation], failure)
+}
+
+// latestRun returns the most recently created Job controlled by the owner
+func (r *WorkloadReconciler) latestRun(ctx context.Context, namespace string, ownerUID types.UID) (*batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed listing jobs: %v", err.Error())
+	}
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if ref := metav1.GetControllerOf(job); ref == nil || ref.UID != ownerUID {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) ||
+			(latest.CreationTimestamp.Equal(&job.CreationTimestamp) && latest.Name < job.Name) {
+			latest = job
+		}
+	}
+	return latest, nil
+}
+
+// ReconcilePod reports annotated pods that are crash looping or failed. Pods of an annotated Deployment or Job are
+// left to the workload, other pods are reported together with the pods sharing their owner
+func (r *WorkloadReconciler) ReconcilePod(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !reportsFailures(pod) {
+		return ctrl.Result{}, nil
+	}
+	owner, workload, err := r.ownerOf(ctx, pod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if workload != nil && workload.GetUID() != pod.UID && reportsFailures(workload) {
+		return ctrl.Result{}, nil
+	}
+
+	siblings := []corev1.Pod{*pod}
+	if owner.UID != pod.UID {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(pod.Namespace)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed listing pods: %v", err.Error())
+		}
+		siblings = nil
+		for _, sibling := range pods.Items {
+			if !reportsFailures(&sibling) {
+				continue
+			}
+			siblingOwner, _, err := r.ownerOf(ctx, &sibling)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if siblingOwner.UID == owner.UID {
+				siblings = append(siblings, sibling)
+			}
+		}
+	}
+
+	var failure *workloadFailure
+	if crashing := crashLoopingPods(siblings); len(crashing) > 0 {
+		failure = &workloadFailure{
+			Reason:  "CrashLoopBackOff",
+			Message: fmt.Sprintf("%d pods are crash looping", len(crashing)),
+			Pods:    crashing,
+		}
+	} else if failed := failedPods(siblings); len(failed) > 0 {
+		failure = &workloadFailure{Reason: "PodFailed", Message: failed[0].Status.Message, Pods: failed}
+	}
+	return ctrl.Result{}, r.report(ctx, pod.Namespace, owner, pod.Annotations[issuesv1.ReportFailuresAnnotation], failure)
+}
+
+// report opens the issue of a failing workload, or closes it once the workload recovered.
+// The body is only written when the issue is opened, so restarts of the pods do not edit the issue over and over
+func (r *WorkloadReconciler) report(ctx context.Context, namespace string, owner metav1.OwnerReference, repo string, failure *workloadFailure) error {
+	log := r.Log.With(zap.String("workload", fmt.Sprintf("%s/%s/%s", owner.Kind, namespace, owner.Name)))
+	issue := &issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Name: failureIssueName(owner), Namespace: namespace}}
+	if failure == nil {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(issue), issue); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if issue.Spec.State == "closed" {
+			return nil
+		}
+		issue.Spec.State = "closed"
+		issue.Spec.StateReason = "completed"
+		if err := r.Update(ctx, issue); err != nil {
+			return fmt.Errorf("failed closing issue %s: %v", issue.Name, err.Error())
+		}
+		log.Info("workload recovered, closing issue")
+		return nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, issue, func() error {
+		if issue.Labels == nil {
+			issue.Labels = map[string]string{}
+		}
+		issue.Labels[issuesv1.SourceLabel] = "workload"
+		//Deleting the workload deletes the issue, whose finalizer closes it on GitHub
+		if !hasOwner(issue.OwnerReferences, owner.UID) {
+			issue.OwnerReferences = append(issue.OwnerReferences, metav1.OwnerReference{
+				APIVersion: owner.APIVersion,
+				Kind:       owner.Kind,
+				Name:       owner.Name,
+				UID:        owner.UID,
+			})
+		}
+		issue.Spec.Repo = repourl.Expand(repo)
+		issue.Spec.Title = fmt.Sprintf("%s %s/%s is failing", owner.Kind, namespace, owner.Name)
+		if issue.Spec.State == "open" {
+			return nil
+		}
+		issue.Spec.Description = r.failureBody(ctx, namespace, owner, failure)
+		issue.Spec.State = "open"
+		issue.Spec.StateReason = ""
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed reporting workload failure: %v", err.Error())
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Info(fmt.Sprintf("workload is failing with %s, issue %s", failure.Reason, result))
+	}
+	return nil
+}
+
+// failureBody describes the failure with the log tail of the failing containers and the latest events
+func (r *WorkloadReconciler) failureBody(ctx context.Context, namespace string, owner metav1.OwnerReference, failure *workloadFailure) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s `%s/%s` is failing: **%s**\n", owner.Kind, namespace, owner.Name, failure.Reason)
+	if failure.Message != "" {
+		fmt.Fprintf(&body, "\n> %s\n", failure.Message)
+	}
+
+	pods := failure.Pods
+	if len(pods) > maxReportedPods {
+		pods = pods[:maxReportedPods]
+	}
+	names := []string{owner.Name}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		for _, status := range append(statuses, pod.Status.ContainerStatuses...) {
+			if status.Ready || (status.State.Waiting == nil && status.State.Terminated == nil) {
+				continue
+			}
+			fmt.Fprintf(&body, "\n### Logs of %s/%s\n\n```\n%s\n```\n", pod.Name, status.Name, r.logTail(ctx, pod, status))
+		}
+	}
+
+	events := r.latestEvents(ctx, namespace, names)
+	if len(events) > 0 {
+		body.WriteString("\n### Events\n\n| Last seen | Type | Reason | Object | Message |\n| --- | --- | --- | --- | --- |\n")
+		for _, event := range events {
+			fmt.Fprintf(&body, "| %s | %s | %s | %s/%s | %s |\n", eventTime(event).UTC().Format("2006-01-02 15:04:05"),
+				event.Type, event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, tableCell(event.Message))
+		}
+	}
+	return body.String()
+}
+
+// logTail returns the last lines logged by a container, of its previous run when it restarted
+func (r *WorkloadReconciler) logTail(ctx context.Context, pod corev1.Pod, status corev1.ContainerStatus) string {
+	lines := r.LogLines
+	raw, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: status.Name,
+		TailLines: &lines,
+		Previous:  status.RestartCount > 0,
+	}).DoRaw(ctx)
+	if err != nil {
+		return fmt.Sprintf("logs unavailable: %v", err.Error())
+	}
+	logs := strings.TrimRight(string(raw), "\n")
+	if len(logs) > maxLogBytes {
+		logs = logs[len(logs)-maxLogBytes:]
+	}
+	//A fence in the logs would end the code block early
+	return strings.ReplaceAll(logs, "```", "'''")
+}
+
+// latestEvents returns the latest events of the named objects, oldest first
+func (r *WorkloadReconciler) latestEvents(ctx context.Context, namespace string, names []string) []corev1.Event {
+	var events []corev1.Event
+	for _, name := range names {
+		list, err := r.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("involvedObject.name", name).String(),
+		})
+		if err != nil {
+			r.Log.Error("failed listing events", zap.String("object", name), zap.Error(err))
+			continue
+		}
+		events = append(events, list.Items...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	return events
+}
+
+// ownerOf returns the workload an issue about the object is filed for: the Deployment or Job owning it, its
+// controller for other kinds, or the object itself. The workload is returned when it could be fetched
+func (r *WorkloadReconciler) ownerOf(ctx context.Context, obj client.Object) (metav1.OwnerReference, client.Object, error) {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		self, err := r.referenceTo(obj)
+		return self, obj, err
+	}
+	var workload client.Object
+	name := ref.Name
+	switch ref.Kind {
+	case "ReplicaSet":
+		//The ReplicaSets of a Deployment are named after it and the pod template hash
+		hash := obj.GetLabels()[appsv1.DefaultDeploymentUniqueLabelKey]
+		if hash == "" || !strings.HasSuffix(ref.Name, "-"+hash) {
+			return *ref, nil, nil
+		}
+		workload = &appsv1.Deployment{}
+		name = strings.TrimSuffix(ref.Name, "-"+hash)
+	case "Job":
+		workload = &batchv1.Job{}
+	default:
+		return *ref, nil, nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}, workload); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return *ref, nil, nil
+		}
+		return metav1.OwnerReference{}, nil, fmt.Errorf("failed fetching %s %s: %v", ref.Kind, name, err.Error())
+	}
+	owner, err := r.referenceTo(workload)
+	return owner, workload, err
+}
+
+func (r *WorkloadReconciler) referenceTo(obj client.Object) (metav1.OwnerReference, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	return metav1.OwnerReference{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: obj.GetName(), UID: obj.GetUID()}, nil
+}
+
+func (r *WorkloadReconciler) selectedPods(ctx context.Context, namespace string, selector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err.Error())
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return nil, fmt.Errorf("failed listing pods: %v", err.Error())
+	}
+	return pods.Items, nil
+}
+
+// workloadForPod maps a pod to the workload of the given kind owning it
+func (r *WorkloadReconciler) workloadForPod(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		owner, _, err := r.ownerOf(ctx, obj)
+		if err != nil || owner.Kind != kind {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}}}
+	}
+}
+
+func reportsFailures(obj client.Object) bool {
+	return obj.GetAnnotations()[issuesv1.ReportFailuresAnnotation] != ""
+}
+
+func crashLoopingPods(pods []corev1.Pod) []corev1.Pod {
+	var crashing []corev1.Pod
+	for _, pod := range pods {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		for _, status := range append(statuses, pod.Status.ContainerStatuses...) {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+				crashing = append(crashing, pod)
+				break
+			}
+		}
+	}
+	return crashing
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func failedPods(pods []corev1.Pod) []corev1.Pod {
+	var failed []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodFailed {
+			failed = append(failed, pod)
+		}
+	}
+	return failed
+}
+
+func unreadyPods(pods []corev1.Pod) []corev1.Pod {
+	var unready []corev1.Pod
+	for _, pod := range pods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+				unready = append(unready, pod)
+			}
+		}
+	}
+	return unready
+}
+
+func hasOwner(refs []metav1.OwnerReference, uid types.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// failureIssueName names the GithubIssue of a workload after its kind and name
+func failureIssueName(owner metav1.OwnerReference) string {
+	name := strings.ToLower(owner.Kind) + "-" + owner.Name
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(name))
+		name = fmt.Sprintf("%s-%08x", name[:validation.DNS1123SubdomainMaxLength-9], h.Sum32())
+	}
+	return name
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	return event.EventTime.Time
+}
+
+func tableCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " ")
+}
+
+// SetupWithManager sets up a controller per workload kind with the Manager.
+func (r *WorkloadReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.LogLines == 0 {
+		r.LogLines = defaultLogLines
+	}
+	annotated := builder.WithPredicates(predicate.NewPredicateFuncs(reportsFailures))
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("deployment_failures").
+		For(&appsv1.Deployment{}, annotated).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.workloadForPod("Deployment"))).
+		Complete(reconcile.Func(r.ReconcileDeployment)); err != nil {
+		return err
+	}
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("job_failures").
+		For(&batchv1.Job{}, annotated).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.workloadForPod("Job"))).
+		Complete(reconcile.Func(r.ReconcileJob)); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pod_failures").
+		For(&corev1.Pod{}, annotated).
+		Complete(reconcile.Func(r.ReconcilePod))
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	. "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func crashLoopingPod(name string, owner metav1.OwnerReference, labels map[string]string) *corev1.Pod {
+	controller := true
+	owner.Controller = &controller
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			UID:             types.UID(name),
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "app",
+				RestartCount: 4,
+				State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}
+}
+
+var _ = Describe("workload controller", func() {
+	Context("When an annotated Deployment is crash looping", func() {
+		It("files a single issue for the Deployment and closes it on recovery", func() {
+			ctx := context.Background()
+			Expect(issuesv1.AddToScheme(scheme.Scheme)).To(Succeed())
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "web",
+					Namespace:   "default",
+					UID:         "web-uid",
+					Annotations: map[string]string{issuesv1.ReportFailuresAnnotation: "test/test"},
+				},
+				Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			}
+			replicaSet := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d9f", UID: "rs-uid"}
+			labels := map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "5d9f"}
+			first := crashLoopingPod("web-5d9f-a", replicaSet, labels)
+			second := crashLoopingPod("web-5d9f-b", replicaSet, labels)
+			c := NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(deployment, first, second).Build()
+			event := &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "web-5d9f-a.1", Namespace: "default"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: first.Name},
+				Type:           corev1.EventTypeWarning,
+				Reason:         "BackOff",
+				Message:        "Back-off restarting failed container",
+				LastTimestamp:  metav1.Now(),
+			}
+			r := &WorkloadReconciler{Client: c, Scheme: scheme.Scheme, Log: TestLog,
+				Clientset: kubefake.NewSimpleClientset(event), LogLines: defaultLogLines}
+
+			By("mapping the pods to the Deployment")
+			for _, pod := range []*corev1.Pod{first, second} {
+				Expect(r.workloadForPod("Deployment")(ctx, pod)).To(ConsistOf(reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"},
+				}))
+			}
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}}
+			_, err := r.ReconcileDeployment(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+
+			issues := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, issues)).To(Succeed())
+			Expect(issues.Items).To(HaveLen(1))
+			issue := issues.Items[0]
+			Expect(issue.Name).To(Equal("deployment-web"))
+			Expect(issue.Spec.Repo).To(Equal("https://github.com/test/test"))
+			Expect(issue.Spec.State).To(Equal("open"))
+			Expect(issue.Spec.Description).To(ContainSubstring("CrashLoopBackOff"))
+			Expect(issue.Spec.Description).To(ContainSubstring("fake logs"))
+			Expect(issue.Spec.Description).To(ContainSubstring("Back-off restarting failed container"))
+			Expect(issue.OwnerReferences).To(HaveLen(1))
+			Expect(issue.OwnerReferences[0].UID).To(Equal(deployment.UID))
+
+			By("leaving annotated pods of the Deployment to it")
+			first.Annotations = map[string]string{issuesv1.ReportFailuresAnnotation: "test/test"}
+			Expect(c.Update(ctx, first)).To(Succeed())
+			_, err = r.ReconcilePod(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: first.Name, Namespace: "default"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.List(ctx, issues)).To(Succeed())
+			Expect(issues.Items).To(HaveLen(1))
+
+			By("closing the issue once the pods recover")
+			for _, pod := range []*corev1.Pod{first, second} {
+				pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+				pod.Status.ContainerStatuses[0].Ready = true
+				Expect(c.Status().Update(ctx, pod)).To(Succeed())
+			}
+			_, err = r.ReconcileDeployment(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Get(ctx, types.NamespacedName{Name: "deployment-web", Namespace: "default"}, &issue)).To(Succeed())
+			Expect(issue.Spec.State).To(Equal("closed"))
+			Expect(issue.Spec.StateReason).To(Equal("completed"))
+		})
+	})
+
+	Context("When an annotated pod without a workload fails", func() {
+		It("files an issue for the pod", func() {
+			ctx := context.Background()
+			Expect(issuesv1.AddToScheme(scheme.Scheme)).To(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "migrate",
+					Namespace:   "default",
+					UID:         "migrate-uid",
+					Annotations: map[string]string{issuesv1.ReportFailuresAnnotation: "https://github.com/test/test"},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodFailed, Message: "exit code 1"},
+			}
+			c := NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(pod).Build()
+			r := &WorkloadReconciler{Client: c, Scheme: scheme.Scheme, Log: TestLog,
+				Clientset: kubefake.NewSimpleClientset(), LogLines: defaultLogLines}
+
+			_, err := r.ReconcilePod(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "migrate", Namespace: "default"}})
+			Expect(err).ToNot(HaveOccurred())
+			issue := &issuesv1.GithubIssue{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "pod-migrate", Namespace: "default"}, issue)).To(Succeed())
+			Expect(issue.Spec.Title).To(Equal("Pod default/migrate is failing"))
+			Expect(issue.Spec.Description).To(ContainSubstring("PodFailed"))
+		})
+	})
+})
+
+var _ = Describe("workload controller", func() {
+	Context("When the runs of an annotated CronJob fail", func() {
+		It("files a single issue for the CronJob and closes it once a run succeeds", func() {
+			ctx := context.Background()
+			Expect(issuesv1.AddToScheme(scheme.Scheme)).To(Succeed())
+			controller := true
+			cronJob := metav1.OwnerReference{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", UID: "backup-uid", Controller: &controller}
+			run := func(name string, minutesAgo int, condition batchv1.JobConditionType) *batchv1.Job {
+				return &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              name,
+						Namespace:         "default",
+						UID:               types.UID(name),
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Duration(minutesAgo) * time.Minute)),
+						Annotations:       map[string]string{issuesv1.ReportFailuresAnnotation: "test/test"},
+						OwnerReferences:   []metav1.OwnerReference{cronJob},
+					},
+					Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}},
+				}
+			}
+			older, newer := run("backup-1", 10, batchv1.JobFailed), run("backup-2", 5, batchv1.JobFailed)
+			c := NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(older, newer).Build()
+			r := &WorkloadReconciler{Client: c, Scheme: scheme.Scheme, Log: TestLog,
+				Clientset: kubefake.NewSimpleClientset(), LogLines: defaultLogLines}
+
+			for _, job := range []*batchv1.Job{older, newer} {
+				_, err := r.ReconcileJob(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(job)})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			issues := &issuesv1.GithubIssueList{}
+			Expect(c.List(ctx, issues)).To(Succeed())
+			Expect(issues.Items).To(HaveLen(1))
+			Expect(issues.Items[0].Name).To(Equal("cronjob-backup"))
+			Expect(issues.Items[0].Spec.Title).To(Equal("CronJob default/backup is failing"))
+			Expect(issues.Items[0].OwnerReferences[0].UID).To(Equal(cronJob.UID))
+
+			By("closing the issue once the latest run succeeds, whatever older runs report")
+			succeeded := run("backup-3", 0, batchv1.JobComplete)
+			Expect(c.Create(ctx, succeeded)).To(Succeed())
+			for _, job := range []*batchv1.Job{succeeded, older} {
+				_, err := r.ReconcileJob(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(job)})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			issue := &issuesv1.GithubIssue{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "cronjob-backup", Namespace: "default"}, issue)).To(Succeed())
+			Expect(issue.Spec.State).To(Equal("closed"))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphql
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBatch keeps queries well below the GraphQL node limit
+const DefaultMaxBatch = 50
+
+// Batcher serves the issues of a repository from a single query. When an issue is fetched, the other pending
+// issues of its repository are fetched along with it, and served to their own reconciles for a while
+type Batcher struct {
+	Client *Client
+	//TTL of the issues fetched along with another one, they are served once
+	TTL time.Duration
+	//MaxBatch is the number of issues fetched by a query, DefaultMaxBatch when zero
+	MaxBatch int
+
+	mu      sync.Mutex
+	entries map[entryKey]*entry
+	//fetched is when each issue was last fetched, issues fetched within the TTL are not fetched along with others
+	fetched map[entryKey]time.Time
+}
+
+// entryKey identifies a fetched issue. Prefetched issues are served once to their own reconcile,
+// served ones once more to the reconcile that fetched them
+type entryKey struct {
+	repo       string
+	number     int
+	prefetched bool
+}
+
+type entry struct {
+	issue     *Issue
+	fetchedAt time.Time
+}
+
+// Get returns an issue, fetching the pending issues of the same repository along with it.
+// It returns nil when the issue does not exist
+func (b *Batcher) Get(ctx context.Context, owner string, repo string, number int, pending []int) (*Issue, error) {
+	key := strings.ToLower(owner + "/" + repo)
+	if issue := b.take(entryKey{key, number, true}); issue != nil {
+		b.put(entryKey{key, number, false}, issue, time.Now())
+		return issue, nil
+	}
+
+	maxBatch := b.MaxBatch
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+	numbers := []int{number}
+	seen := map[int]bool{number: true}
+	b.mu.Lock()
+	for _, peer := range pending {
+		if len(numbers) >= maxBatch {
+			break
+		}
+		if seen[peer] || time.Since(b.fetched[entryKey{key, peer, false}]) < b.TTL {
+			continue
+		}
+		seen[peer] = true
+		numbers = append(numbers, peer)
+	}
+	b.mu.Unlock()
+
+	issues, err := b.Client.Issues(ctx, owner, repo, numbers)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	b.mu.Lock()
+	if b.fetched == nil {
+		b.fetched = map[entryKey]time.Time{}
+	}
+	for _, fetched := range numbers {
+		b.fetched[entryKey{key, fetched, false}] = now
+	}
+	b.mu.Unlock()
+	for _, peer := range numbers[1:] {
+		if issue, ok := issues[peer]; ok {
+			b.put(entryKey{key, peer, true}, issue, now)
+		}
+	}
+	issue := issues[number]
+	if issue != nil {
+		b.put(entryKey{key, number, false}, issue, now)
+	}
+	return issue, nil
+}
+
+// Served returns the issue last returned by Get, once, so that the same reconcile reuses its linked pull requests
+func (b *Batcher) Served(owner string, repo string, number int) *Issue {
+	return b.take(entryKey{strings.ToLower(owner + "/" + repo), number, false})
+}
+
+// Forget drops the fetched state of an issue, it is called once the issue is edited
+func (b *Batcher) Forget(owner string, repo string, number int) {
+	key := strings.ToLower(owner + "/" + repo)
+	b.take(entryKey{key, number, true})
+	b.take(entryKey{key, number, false})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.fetched, entryKey{key, number, false})
+}
+
+func (b *Batcher) fresh(cached *entry, now time.Time) bool {
+	return cached != nil && now.Sub(cached.fetchedAt) < b.TTL
+}
+
+func (b *Batcher) take(key entryKey) *Issue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cached := b.entries[key]
+	delete(b.entries, key)
+	if !b.fresh(cached, time.Now()) {
+		return nil
+	}
+	return cached.issue
+}
+
+func (b *Batcher) put(key entryKey, issue *Issue, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.entries == nil {
+		b.entries = map[entryKey]*entry{}
+	}
+	b.entries[key] = &entry{issue: issue, fetchedAt: now}
+	//Drop expired issues, of issues that were deleted or moved to another shard
+	for cachedKey, cached := range b.entries {
+		if !b.fresh(cached, now) {
+			delete(b.entries, cachedKey)
+		}
+	}
+	for fetchedKey, fetchedAt := range b.fetched {
+		if now.Sub(fetchedAt) >= b.TTL {
+			delete(b.fetched, fetchedKey)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package graphql fetches the state of many issues of a repository in one GitHub GraphQL v4 query
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+)
+
+// issueFields are fetched for every issue of a query
+const issueFields = `number url title body state stateReason updatedAt
+milestone { number }
+labels(first: 100) { nodes { name } }
+assignees(first: 20) { nodes { login } }
+comments { totalCount }
+reactionGroups { content reactors { totalCount } }
+timelineItems(first: 50, itemTypes: [CROSS_REFERENCED_EVENT]) {
+  nodes { ... on CrossReferencedEvent { source { ... on PullRequest { number url state mergedAt } } } }
+}
+closedByPullRequestsReferences(first: 20, includeClosedPrs: true) { nodes { number url state mergedAt } }`
+
+// Client queries the GitHub GraphQL API
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+}
+
+// NewClient returns a client sharing the authenticated http client of a REST client. The endpoint is derived from
+// the REST base url: api.github.com serves /graphql and GitHub Enterprise serves /api/graphql next to /api/v3
+func NewClient(rest *github.Client) *Client {
+	endpoint := *rest.BaseURL
+	if strings.HasSuffix(strings.TrimSuffix(endpoint.Path, "/"), "/api/v3") {
+		endpoint.Path = strings.TrimSuffix(strings.TrimSuffix(endpoint.Path, "/"), "/v3") + "/graphql"
+	} else {
+		endpoint = *endpoint.ResolveReference(&url.URL{Path: "graphql"})
+	}
+	return &Client{HTTP: rest.Client(), Endpoint: endpoint.String()}
+}
+
+// PullRequest is a pull request cross-referencing an issue
+type PullRequest struct {
+	Number   int        `json:"number"`
+	URL      string     `json:"url"`
+	State    string     `json:"state"`
+	MergedAt *time.Time `json:"mergedAt"`
+	//Closes is set for pull requests that close the issue, with a closing keyword or a link made from the sidebar
+	Closes bool `json:"-"`
+}
+
+// Issue is the state of an issue
+type Issue struct {
+	Number      int       `json:"number"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	StateReason string    `json:"stateReason"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Milestone   *struct {
+		Number int `json:"number"`
+	} `json:"milestone"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []struct {
+			Login string `json:"login"`
+		} `json:"nodes"`
+	} `json:"assignees"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+	ReactionGroups []struct {
+		Content  string `json:"content"`
+		Reactors struct {
+			TotalCount int `json:"totalCount"`
+		} `json:"reactors"`
+	} `json:"reactionGroups"`
+	TimelineItems struct {
+		Nodes []struct {
+			Source *PullRequest `json:"source"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	ClosedBy struct {
+		Nodes []PullRequest `json:"nodes"`
+	} `json:"closedByPullRequestsReferences"`
+}
+
+// LinkedPRs returns the pull requests cross-referencing or closing the issue, once each
+func (i *Issue) LinkedPRs() []PullRequest {
+	var linked []PullRequest
+	seen := map[string]bool{}
+	for _, pr := range i.ClosedBy.Nodes {
+		if seen[pr.URL] {
+			continue
+		}
+		pr.Closes = true
+		seen[pr.URL] = true
+		linked = append(linked, pr)
+	}
+	for _, node := range i.TimelineItems.Nodes {
+		//Sources that are issues decode to an empty pull request
+		if node.Source == nil || node.Source.URL == "" || seen[node.Source.URL] {
+			continue
+		}
+		seen[node.Source.URL] = true
+		linked = append(linked, *node.Source)
+	}
+	return linked
+}
+
+// REST converts the issue to the type returned by the REST API, lower casing its state and state reason
+func (i *Issue) REST() *github.Issue {
+	issue := &github.Issue{
+		Number:    github.Int(i.Number),
+		HTMLURL:   github.String(i.URL),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Body),
+		State:     github.String(strings.ToLower(i.State)),
+		Comments:  github.Int(i.Comments.TotalCount),
+		UpdatedAt: &github.Timestamp{Time: i.UpdatedAt},
+		Reactions: &github.Reactions{},
+	}
+	if i.StateReason != "" {
+		issue.StateReason = github.String(strings.ToLower(i.StateReason))
+	}
+	if i.Milestone != nil {
+		issue.Milestone = &github.Milestone{Number: github.Int(i.Milestone.Number)}
+	}
+	for _, label := range i.Labels.Nodes {
+		issue.Labels = append(issue.Labels, &github.Label{Name: github.String(label.Name)})
+	}
+	for _, assignee := range i.Assignees.Nodes {
+		issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(assignee.Login)})
+	}
+	reactions := issue.Reactions
+	total := 0
+	for _, group := range i.ReactionGroups {
+		count := github.Int(group.Reactors.TotalCount)
+		total += group.Reactors.TotalCount
+		switch group.Content {
+		case "THUMBS_UP":
+			reactions.PlusOne = count
+		case "THUMBS_DOWN":
+			reactions.MinusOne = count
+		case "LAUGH":
+			reactions.Laugh = count
+		case "CONFUSED":
+			reactions.Confused = count
+		case "HEART":
+			reactions.Heart = count
+		case "HOORAY":
+			reactions.Hooray = count
+		case "ROCKET":
+			reactions.Rocket = count
+		case "EYES":
+			reactions.Eyes = count
+		}
+	}
+	reactions.TotalCount = github.Int(total)
+	return issue
+}
+
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type response struct {
+	Data struct {
+		Repository map[string]*Issue `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Type    string        `json:"type"`
+		Path    []interface{} `json:"path"`
+		Message string        `json:"message"`
+	} `json:"errors"`
+}
+
+// Issues fetches the issues of a repository by number. Issues that do not exist are missing from the result
+func (c *Client) Issues(ctx context.Context, owner string, repo string, numbers []int) (map[int]*Issue, error) {
+	if len(numbers) == 0 {
+		return map[int]*Issue{}, nil
+	}
+	var query strings.Builder
+	query.WriteString("query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) {\n")
+	for _, number := range numbers {
+		fmt.Fprintf(&query, "i%d: issue(number: %d) { %s }\n", number, number, issueFields)
+	}
+	query.WriteString("} }")
+	body, err := json.Marshal(request{Query: query.String(), Variables: map[string]interface{}{"owner": owner, "name": repo}})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying issues: %v", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed querying issues: status %s", resp.Status)
+	}
+	result := &response{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("invalid graphql response: %v", err.Error())
+	}
+	for _, queryErr := range result.Errors {
+		//A missing issue fails its own field only
+		if queryErr.Type != "NOT_FOUND" || len(queryErr.Path) < 2 {
+			return nil, fmt.Errorf("failed querying issues: %s", queryErr.Message)
+		}
+	}
+
+	issues := map[int]*Issue{}
+	for _, issue := range result.Data.Repository {
+		if issue != nil {
+			issues[issue.Number] = issue
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,161 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var aliasPattern = regexp.MustCompile(`i(\d+): issue`)
+
+// fakeGitHub answers queries for the issues 1 to 9 of test/test, the others do not exist
+type fakeGitHub struct {
+	queries [][]int
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &request{}
+	Expect(json.NewDecoder(req.Body).Decode(body)).To(Succeed())
+	Expect(body.Variables).To(Equal(map[string]interface{}{"owner": "test", "name": "test"}))
+	var numbers []int
+	repository := map[string]interface{}{}
+	var errors []map[string]interface{}
+	for _, match := range aliasPattern.FindAllStringSubmatch(body.Query, -1) {
+		number, _ := strconv.Atoi(match[1])
+		numbers = append(numbers, number)
+		alias := "i" + match[1]
+		if number > 9 {
+			repository[alias] = nil
+			errors = append(errors, map[string]interface{}{"type": "NOT_FOUND", "path": []string{"repository", alias},
+				"message": fmt.Sprintf("Could not resolve to an issue with the number of %d.", number)})
+			continue
+		}
+		repository[alias] = map[string]interface{}{
+			"number": number, "url": fmt.Sprintf("https://github.com/test/test/issues/%d", number),
+			"title": fmt.Sprintf("issue %d", number), "state": "CLOSED", "stateReason": "NOT_PLANNED",
+			"updatedAt": "2024-05-01T10:00:00Z",
+			"labels":    map[string]interface{}{"nodes": []map[string]string{{"name": "bug"}}},
+			"comments":  map[string]interface{}{"totalCount": 3},
+			"reactionGroups": []map[string]interface{}{
+				{"content": "THUMBS_UP", "reactors": map[string]int{"totalCount": 2}},
+				{"content": "EYES", "reactors": map[string]int{"totalCount": 1}},
+			},
+			"timelineItems": map[string]interface{}{"nodes": []map[string]interface{}{
+				{"source": map[string]interface{}{"number": 20, "url": "https://github.com/test/test/pull/20", "state": "MERGED", "mergedAt": "2024-05-01T09:00:00Z"}},
+				{"source": map[string]interface{}{}},
+				{},
+			}},
+			"closedByPullRequestsReferences": map[string]interface{}{"nodes": []map[string]interface{}{
+				{"number": 21, "url": "https://github.com/test/test/pull/21", "state": "OPEN"},
+			}},
+		}
+	}
+	f.queries = append(f.queries, numbers)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"repository": repository}, "errors": errors})
+}
+
+var _ = Describe("GraphQL client", func() {
+	var (
+		fake   *fakeGitHub
+		server *httptest.Server
+		client *Client
+		ctx    = context.Background()
+	)
+
+	BeforeEach(func() {
+		fake = &fakeGitHub{}
+		server = httptest.NewServer(fake)
+		client = &Client{HTTP: server.Client(), Endpoint: server.URL}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("derives the endpoint from the REST client", func() {
+		Expect(NewClient(github.NewClient(nil)).Endpoint).To(Equal("https://api.github.com/graphql"))
+		enterprise, err := github.NewClient(nil).WithEnterpriseURLs("https://github.example.com/api/v3/", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(NewClient(enterprise).Endpoint).To(Equal("https://github.example.com/api/graphql"))
+	})
+
+	It("fetches many issues in one query, leaving out missing ones", func() {
+		issues, err := client.Issues(ctx, "test", "test", []int{1, 2, 10})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.queries).To(Equal([][]int{{1, 2, 10}}))
+		Expect(issues).To(HaveLen(2))
+
+		issue := issues[2].REST()
+		Expect(issue.GetState()).To(Equal("closed"))
+		Expect(issue.GetStateReason()).To(Equal("not_planned"))
+		Expect(issue.GetHTMLURL()).To(Equal("https://github.com/test/test/issues/2"))
+		Expect(issue.Labels[0].GetName()).To(Equal("bug"))
+		Expect(issue.GetComments()).To(Equal(3))
+		Expect(issue.GetReactions().GetTotalCount()).To(Equal(3))
+		Expect(issue.GetReactions().GetPlusOne()).To(Equal(2))
+
+		linked := issues[2].LinkedPRs()
+		Expect(linked).To(HaveLen(2))
+		Expect(linked[0].Number).To(Equal(21))
+		Expect(linked[0].Closes).To(BeTrue())
+		Expect(linked[1].State).To(Equal("MERGED"))
+		Expect(linked[1].Closes).To(BeFalse())
+		Expect(*linked[1].MergedAt).To(Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)))
+	})
+
+	It("fails on errors other than missing issues", func() {
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`))
+		})
+		_, err := client.Issues(ctx, "test", "test", []int{1})
+		Expect(err).To(MatchError(ContainSubstring("rate limit")))
+	})
+
+	It("serves the pending issues of a repository from the query of another", func() {
+		batcher := &Batcher{Client: client, TTL: time.Minute}
+		issue, err := batcher.Get(ctx, "test", "test", 1, []int{2, 3, 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issue.Number).To(Equal(1))
+		Expect(batcher.Served("test", "test", 1)).To(Equal(issue))
+		Expect(batcher.Served("test", "test", 1)).To(BeNil())
+
+		By("serving a prefetched issue once")
+		issue, err = batcher.Get(ctx, "Test", "Test", 2, []int{1, 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issue.Number).To(Equal(2))
+		Expect(fake.queries).To(Equal([][]int{{1, 2, 3}}))
+		_, err = batcher.Get(ctx, "test", "test", 2, []int{1, 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.queries).To(Equal([][]int{{1, 2, 3}, {2}}))
+
+		By("fetching an edited issue again")
+		batcher.Forget("test", "test", 3)
+		_, err = batcher.Get(ctx, "test", "test", 4, []int{3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.queries).To(Equal([][]int{{1, 2, 3}, {2}, {4, 3}}))
+
+		By("returning nothing for a missing issue")
+		issue, err = batcher.Get(ctx, "test", "test", 10, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issue).To(BeNil())
+	})
+
+	It("fetches pending issues again once they expire", func() {
+		batcher := &Batcher{Client: client, TTL: time.Millisecond, MaxBatch: 2}
+		_, err := batcher.Get(ctx, "test", "test", 1, []int{2, 3})
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(2 * time.Millisecond)
+		_, err = batcher.Get(ctx, "test", "test", 2, []int{1, 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.queries).To(Equal([][]int{{1, 2}, {2, 1}}))
+	})
+})
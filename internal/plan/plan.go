@@ -0,0 +1,189 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plan computes the GitHub mutations the reconciler makes to bring an issue to its desired state,
+// shared by dry runs of the controller and the plan command of the kubectl plugin
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+)
+
+const (
+	ActionNone   = "none"
+	ActionCreate = "create"
+	ActionEdit   = "edit"
+	ActionClose  = "close"
+)
+
+// Issue is the desired state of the fields of an issue the reconciler manages. Empty labels and assignees, a nil
+// milestone and an empty state leave the issue untouched, as they do when editing
+type Issue struct {
+	Title       string
+	Body        string
+	Labels      []string
+	Assignees   []string
+	Milestone   *int
+	State       string
+	StateReason string
+}
+
+// Plan is the mutation planned for an issue, with a line per changed field
+type Plan struct {
+	Action string
+	Number int
+	Diff   []string
+}
+
+// Compute plans the mutation of current, the issue found on GitHub or nil, into desired
+func Compute(current *github.Issue, desired Issue) Plan {
+	if current == nil {
+		if desired.State == "closed" {
+			return Plan{Action: ActionNone}
+		}
+		diff := []string{fmt.Sprintf("title: %q", desired.Title)}
+		if desired.Body != "" {
+			diff = append(diff, fmt.Sprintf("body: %d lines", len(lines(desired.Body))))
+		}
+		if len(desired.Labels) > 0 {
+			diff = append(diff, fmt.Sprintf("labels: %v", desired.Labels))
+		}
+		if len(desired.Assignees) > 0 {
+			diff = append(diff, fmt.Sprintf("assignees: %v", desired.Assignees))
+		}
+		if desired.Milestone != nil {
+			diff = append(diff, fmt.Sprintf("milestone: #%d", *desired.Milestone))
+		}
+		return Plan{Action: ActionCreate, Diff: diff}
+	}
+
+	p := Plan{Action: ActionEdit, Number: current.GetNumber()}
+	if current.GetTitle() != desired.Title {
+		p.Diff = append(p.Diff, fmt.Sprintf("title: %q -> %q", current.GetTitle(), desired.Title))
+	}
+	if current.GetBody() != desired.Body {
+		added, removed := lineChanges(current.GetBody(), desired.Body)
+		p.Diff = append(p.Diff, fmt.Sprintf("body: +%d -%d lines", added, removed))
+	}
+	var labels []string
+	for _, label := range current.Labels {
+		labels = append(labels, label.GetName())
+	}
+	if len(desired.Labels) > 0 && !sameSet(labels, desired.Labels) {
+		p.Diff = append(p.Diff, fmt.Sprintf("labels: %v -> %v", sorted(labels), sorted(desired.Labels)))
+	}
+	var assignees []string
+	for _, assignee := range current.Assignees {
+		assignees = append(assignees, assignee.GetLogin())
+	}
+	if len(desired.Assignees) > 0 && !sameSet(assignees, desired.Assignees) {
+		p.Diff = append(p.Diff, fmt.Sprintf("assignees: %v -> %v", sorted(assignees), sorted(desired.Assignees)))
+	}
+	if desired.Milestone != nil && current.GetMilestone().GetNumber() != *desired.Milestone {
+		p.Diff = append(p.Diff, fmt.Sprintf("milestone: #%d -> #%d", current.GetMilestone().GetNumber(), *desired.Milestone))
+	}
+	if desired.State != "" && desired.State != current.GetState() {
+		state := fmt.Sprintf("state: %s -> %s", current.GetState(), desired.State)
+		if desired.State == "closed" {
+			p.Action = ActionClose
+			if desired.StateReason != "" {
+				state += fmt.Sprintf(" (%s)", desired.StateReason)
+			}
+		}
+		p.Diff = append(p.Diff, state)
+	}
+	if len(p.Diff) == 0 {
+		p.Action = ActionNone
+	}
+	return p
+}
+
+// Close plans closing an issue, as the reconciler does when a GithubIssue is deleted
+func Close(current *github.Issue) Plan {
+	if current == nil || current.GetState() == "closed" {
+		return Plan{Action: ActionNone}
+	}
+	return Plan{Action: ActionClose, Number: current.GetNumber(), Diff: []string{"state: open -> closed"}}
+}
+
+// String describes the plan on a single line
+func (p Plan) String() string {
+	switch p.Action {
+	case ActionNone:
+		return "no changes"
+	case ActionCreate:
+		return "create issue: " + strings.Join(p.Diff, ", ")
+	default:
+		return fmt.Sprintf("%s issue #%d: %s", p.Action, p.Number, strings.Join(p.Diff, ", "))
+	}
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// lineChanges counts the lines added to and removed from a text, regardless of their order
+func lineChanges(from string, to string) (added int, removed int) {
+	count := map[string]int{}
+	for _, line := range lines(from) {
+		count[line]++
+	}
+	for _, line := range lines(to) {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		added++
+	}
+	for _, n := range count {
+		removed += n
+	}
+	return added, removed
+}
+
+func sameSet(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA, sortedB := sorted(lower(a)), sorted(lower(b))
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lower(s []string) []string {
+	out := make([]string, 0, len(s))
+	for _, item := range s {
+		out = append(out, strings.ToLower(item))
+	}
+	return out
+}
+
+func sorted(s []string) []string {
+	out := append([]string{}, s...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,91 @@
+package plan
+
+import (
+	"github.com/google/go-github/v56/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Issue plans", func() {
+	current := func() *github.Issue {
+		return &github.Issue{
+			Number:    github.Int(3),
+			Title:     github.String("Disk full"),
+			Body:      github.String("first\nsecond"),
+			State:     github.String("open"),
+			Labels:    []*github.Label{{Name: github.String("bug")}, {Name: github.String("ops")}},
+			Assignees: []*github.User{{Login: github.String("alice")}},
+			Milestone: &github.Milestone{Number: github.Int(1)},
+		}
+	}
+	desired := func() Issue {
+		return Issue{Title: "Disk full", Body: "first\nsecond"}
+	}
+
+	DescribeTable("computing the plan of an existing issue",
+		func(change func(*Issue), action string, diff []string) {
+			issue := desired()
+			change(&issue)
+			p := Compute(current(), issue)
+			Expect(p.Action).To(Equal(action))
+			Expect(p.Diff).To(Equal(diff))
+		},
+		Entry("no changes", func(*Issue) {}, ActionNone, nil),
+		Entry("a new title", func(i *Issue) { i.Title = "Disk almost full" },
+			ActionEdit, []string{`title: "Disk full" -> "Disk almost full"`}),
+		Entry("a changed body", func(i *Issue) { i.Body = "second\nthird\nfourth" },
+			ActionEdit, []string{"body: +2 -1 lines"}),
+		Entry("the same labels in another order and case", func(i *Issue) { i.Labels = []string{"OPS", "bug"} }, ActionNone, nil),
+		Entry("other labels", func(i *Issue) { i.Labels = []string{"ops", "sev1"} },
+			ActionEdit, []string{"labels: [bug ops] -> [ops sev1]"}),
+		Entry("other assignees", func(i *Issue) { i.Assignees = []string{"bob"} },
+			ActionEdit, []string{"assignees: [alice] -> [bob]"}),
+		Entry("another milestone", func(i *Issue) { i.Milestone = github.Int(2) },
+			ActionEdit, []string{"milestone: #1 -> #2"}),
+		Entry("closing", func(i *Issue) { i.State, i.StateReason = "closed", "completed" },
+			ActionClose, []string{"state: open -> closed (completed)"}),
+	)
+
+	DescribeTable("computing the plan of a missing issue",
+		func(issue Issue, action string, diff []string) {
+			p := Compute(nil, issue)
+			Expect(p.Action).To(Equal(action))
+			Expect(p.Diff).To(Equal(diff))
+		},
+		Entry("a closed issue", Issue{Title: "Disk full", State: "closed"}, ActionNone, nil),
+		Entry("a title only", Issue{Title: "Disk full"}, ActionCreate, []string{`title: "Disk full"`}),
+		Entry("every field", Issue{Title: "Disk full", Body: "a\nb", Labels: []string{"bug"}, Assignees: []string{"alice"}, Milestone: github.Int(1)},
+			ActionCreate, []string{`title: "Disk full"`, "body: 2 lines", "labels: [bug]", "assignees: [alice]", "milestone: #1"}),
+	)
+
+	DescribeTable("closing an issue",
+		func(issue *github.Issue, action string) {
+			Expect(Close(issue).Action).To(Equal(action))
+		},
+		Entry("a missing issue", nil, ActionNone),
+		Entry("a closed issue", &github.Issue{Number: github.Int(3), State: github.String("closed")}, ActionNone),
+		Entry("an open issue", &github.Issue{Number: github.Int(3), State: github.String("open")}, ActionClose),
+	)
+
+	DescribeTable("describing a plan",
+		func(p Plan, description string) {
+			Expect(p.String()).To(Equal(description))
+		},
+		Entry("no changes", Plan{Action: ActionNone}, "no changes"),
+		Entry("a create", Plan{Action: ActionCreate, Diff: []string{`title: "a"`, "labels: [bug]"}}, `create issue: title: "a", labels: [bug]`),
+		Entry("an edit", Plan{Action: ActionEdit, Number: 3, Diff: []string{"milestone: #1 -> #2"}}, "edit issue #3: milestone: #1 -> #2"),
+	)
+
+	DescribeTable("counting the changed lines",
+		func(from string, to string, added int, removed int) {
+			gotAdded, gotRemoved := lineChanges(from, to)
+			Expect([]int{gotAdded, gotRemoved}).To(Equal([]int{added, removed}))
+		},
+		Entry("the same text", "a\nb", "a\nb", 0, 0),
+		Entry("reordered lines", "a\nb", "b\na", 0, 0),
+		Entry("windows line endings", "a\r\nb", "a\nb", 0, 0),
+		Entry("a repeated line", "a", "a\na", 1, 0),
+		Entry("from empty", "", "a\nb", 2, 0),
+		Entry("to empty", "a\nb", "", 0, 2),
+	)
+})
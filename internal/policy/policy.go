@@ -0,0 +1,234 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates the GithubIssuePolicy objects restricting the repos, labels and open issues of namespaces
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Fields of the spec a Violation can point at. FieldSpec is used for the open issue limit
+const (
+	FieldRepo   = "repo"
+	FieldLabels = "labels"
+	FieldSpec   = ""
+)
+
+// Violation is a part of a GithubIssue that a policy does not allow
+type Violation struct {
+	//Policy is the name of the GithubIssuePolicy that does not allow it
+	Policy string
+	//Field of the spec at fault, one of the Field constants
+	Field string
+	//Index of the offending label, -1 for the other fields
+	Index   int
+	Message string
+}
+
+// DeniedError is returned for issues that no policy selecting their namespace allows
+type DeniedError struct {
+	Violations []Violation
+}
+
+func (e *DeniedError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "denied by policy: " + strings.Join(messages, "; ")
+}
+
+// Check returns a *DeniedError when the policies selecting the namespace of the issue do not allow it.
+// Issues being deleted and issues of namespaces selected by no policy are always allowed
+func Check(ctx context.Context, c client.Reader, issue *issuesv1.GithubIssue) error {
+	if !issue.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	policies, err := selecting(ctx, c, issue.Namespace)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+	key, err := repourl.Key(issue.Spec.Repo)
+	if err != nil {
+		return err
+	}
+	var openBefore int
+	if open(issue) && limited(policies) {
+		if openBefore, err = countOpenBefore(ctx, c, issue); err != nil {
+			return err
+		}
+	}
+
+	return decide(policies, func(policy issuesv1.GithubIssuePolicy) []Violation {
+		return evaluate(policy, key, issue.Spec.Labels, open(issue), openBefore)
+	})
+}
+
+// CheckTarget returns a *DeniedError when the policies selecting the namespace do not allow writing to the repo with
+// the labels. It checks the objects other than GithubIssue that write to GitHub, the open issue limit does not apply to them
+func CheckTarget(ctx context.Context, c client.Reader, namespace string, repoURL string, labels []string) error {
+	policies, err := selecting(ctx, c, namespace)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+	key, err := repourl.Key(repoURL)
+	if err != nil {
+		return err
+	}
+	return decide(policies, func(policy issuesv1.GithubIssuePolicy) []Violation {
+		return evaluate(policy, key, labels, false, 0)
+	})
+}
+
+// decide allows what any of the policies allows, and returns the violations of every policy otherwise
+func decide(policies []issuesv1.GithubIssuePolicy, evaluate func(issuesv1.GithubIssuePolicy) []Violation) error {
+	denied := &DeniedError{}
+	for _, policy := range policies {
+		violations := evaluate(policy)
+		if len(violations) == 0 {
+			return nil
+		}
+		denied.Violations = append(denied.Violations, violations...)
+	}
+	return denied
+}
+
+// selecting returns the policies whose namespace selector matches the namespace, ordered by name
+func selecting(ctx context.Context, c client.Reader, namespaceName string) ([]issuesv1.GithubIssuePolicy, error) {
+	policyList := &issuesv1.GithubIssuePolicyList{}
+	if err := c.List(ctx, policyList); err != nil {
+		return nil, fmt.Errorf("failed listing issue policies: %v", err.Error())
+	}
+	if len(policyList.Items) == 0 {
+		return nil, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); err != nil {
+		return nil, fmt.Errorf("failed fetching namespace %s: %v", namespaceName, err.Error())
+	}
+	var policies []issuesv1.GithubIssuePolicy
+	for _, policy := range policyList.Items {
+		if policy.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace selector in %s: %v", policy.Name, err.Error())
+			}
+			if !selector.Matches(labels.Set(namespace.Labels)) {
+				continue
+			}
+		}
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies, nil
+}
+
+// evaluate returns what the policy does not allow of the repo and labels. isOpen tells whether they are those of an
+// open issue, and openBefore the number of open issues of the namespace that take precedence over it
+func evaluate(policy issuesv1.GithubIssuePolicy, repoKey string, labels []string, isOpen bool, openBefore int) []Violation {
+	var violations []Violation
+	if len(policy.Spec.Repos) > 0 && !matchesAny(policy.Spec.Repos, repoKey) {
+		violations = append(violations, Violation{Policy: policy.Name, Field: FieldRepo, Index: -1,
+			Message: fmt.Sprintf("%s does not allow repo %s", policy.Name, repoKey)})
+	}
+	if len(policy.Spec.Labels) > 0 {
+		for i, label := range labels {
+			if !matchesAny(policy.Spec.Labels, strings.ToLower(label)) {
+				violations = append(violations, Violation{Policy: policy.Name, Field: FieldLabels, Index: i,
+					Message: fmt.Sprintf("%s does not allow label %q", policy.Name, label)})
+			}
+		}
+	}
+	if policy.Spec.MaxOpenIssues > 0 && isOpen && openBefore >= policy.Spec.MaxOpenIssues {
+		violations = append(violations, Violation{Policy: policy.Name, Field: FieldSpec, Index: -1,
+			Message: fmt.Sprintf("%s allows %d open issues per namespace and %d are open", policy.Name, policy.Spec.MaxOpenIssues, openBefore)})
+	}
+	return violations
+}
+
+// matchesAny matches the lowercase value against the globs, ignoring case
+func matchesAny(globs []string, value string) bool {
+	for _, glob := range globs {
+		if matched, err := path.Match(strings.ToLower(glob), value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func limited(policies []issuesv1.GithubIssuePolicy) bool {
+	for _, policy := range policies {
+		if policy.Spec.MaxOpenIssues > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// open reports whether the issue is, or is going to be, open on GitHub
+func open(issue *issuesv1.GithubIssue) bool {
+	if !issue.DeletionTimestamp.IsZero() || issue.Spec.State == "closed" {
+		return false
+	}
+	condition := meta.FindStatusCondition(issue.Status.Conditions, "IssueIsOpen")
+	return issue.Spec.State == "open" || condition == nil || condition.Status != metav1.ConditionFalse
+}
+
+// countOpenBefore counts the open issues of the namespace created before the issue. Issues not created yet come last
+func countOpenBefore(ctx context.Context, c client.Reader, issue *issuesv1.GithubIssue) (int, error) {
+	issueList := &issuesv1.GithubIssueList{}
+	if err := c.List(ctx, issueList, client.InNamespace(issue.Namespace)); err != nil {
+		return 0, fmt.Errorf("failed listing issues of %s: %v", issue.Namespace, err.Error())
+	}
+	count := 0
+	for i := range issueList.Items {
+		other := &issueList.Items[i]
+		if other.Name == issue.Name || !open(other) {
+			continue
+		}
+		if before(other, issue) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// before orders issues by creation time then name, issues not created yet are last
+func before(a *issuesv1.GithubIssue, b *issuesv1.GithubIssue) bool {
+	switch {
+	case b.CreationTimestamp.IsZero():
+		return true
+	case a.CreationTimestamp.IsZero():
+		return false
+	case !a.CreationTimestamp.Equal(&b.CreationTimestamp):
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newIssue(name string, repo string, created time.Time) *issuesv1.GithubIssue {
+	return &issuesv1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "payments", CreationTimestamp: metav1.NewTime(created)},
+		Spec:       issuesv1.GithubIssueSpec{Repo: repo, Title: name},
+	}
+}
+
+func newClient(objects ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(issuesv1.AddToScheme(s)).To(Succeed())
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}}
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(append(objects, namespace)...).Build()
+}
+
+var _ = Describe("Issue policies", func() {
+	ctx := context.Background()
+	now := time.Now()
+	teamPolicy := &issuesv1.GithubIssuePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+		Spec: issuesv1.GithubIssuePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			Repos:             []string{"acme/payments-*"},
+			Labels:            []string{"bug", "area/*"},
+			MaxOpenIssues:     2,
+		},
+	}
+
+	It("allows every issue of namespaces selected by no policy", func() {
+		other := teamPolicy.DeepCopy()
+		other.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "checkout"}}
+		issue := newIssue("anything", "https://github.com/someone/else", now)
+		Expect(Check(ctx, newClient(other), issue)).To(Succeed())
+	})
+
+	It("matches repos and labels against the globs of the policy", func() {
+		c := newClient(teamPolicy)
+		issue := newIssue("allowed", "https://github.com/ACME/Payments-API", now)
+		issue.Spec.Labels = []string{"Bug", "area/ledger"}
+		Expect(Check(ctx, c, issue)).To(Succeed())
+
+		issue.Spec.Repo = "https://github.com/acme/checkout"
+		issue.Spec.Labels = []string{"bug", "urgent"}
+		err := Check(ctx, c, issue)
+		denied := &DeniedError{}
+		Expect(errors.As(err, &denied)).To(BeTrue())
+		Expect(denied.Violations).To(ConsistOf(
+			Violation{Policy: "payments", Field: FieldRepo, Index: -1, Message: "payments does not allow repo acme/checkout"},
+			Violation{Policy: "payments", Field: FieldLabels, Index: 1, Message: `payments does not allow label "urgent"`},
+		))
+	})
+
+	It("allows an issue when any selecting policy allows it", func() {
+		shared := &issuesv1.GithubIssuePolicy{ObjectMeta: metav1.ObjectMeta{Name: "shared"}, Spec: issuesv1.GithubIssuePolicySpec{Repos: []string{"acme/shared"}}}
+		issue := newIssue("shared", "https://github.com/acme/shared", now)
+		Expect(Check(ctx, newClient(teamPolicy, shared), issue)).To(Succeed())
+	})
+
+	It("checks the repo and labels of the other objects writing to GitHub, without the open issue limit", func() {
+		open := []client.Object{teamPolicy}
+		for _, name := range []string{"first", "second", "third"} {
+			open = append(open, newIssue(name, "https://github.com/acme/payments-api", now))
+		}
+		c := newClient(open...)
+		Expect(CheckTarget(ctx, c, "payments", "https://github.com/acme/payments-api", []string{"area/ledger"})).To(Succeed())
+
+		err := CheckTarget(ctx, c, "payments", "https://github.com/acme/checkout", []string{"urgent"})
+		denied := &DeniedError{}
+		Expect(errors.As(err, &denied)).To(BeTrue())
+		Expect(denied.Violations).To(ConsistOf(
+			Violation{Policy: "payments", Field: FieldRepo, Index: -1, Message: "payments does not allow repo acme/checkout"},
+			Violation{Policy: "payments", Field: FieldLabels, Index: 0, Message: `payments does not allow label "urgent"`},
+		))
+	})
+
+	It("denies the issues opened past the limit, newest first", func() {
+		oldest := newIssue("oldest", "https://github.com/acme/payments-api", now.Add(-2*time.Hour))
+		older := newIssue("older", "https://github.com/acme/payments-api", now.Add(-time.Hour))
+		closed := newIssue("closed", "https://github.com/acme/payments-api", now.Add(-3*time.Hour))
+		closed.Spec.State = "closed"
+		newest := newIssue("newest", "https://github.com/acme/payments-api", now)
+		c := newClient(teamPolicy, oldest, older, closed, newest)
+
+		Expect(Check(ctx, c, oldest)).To(Succeed())
+		Expect(Check(ctx, c, older)).To(Succeed())
+		Expect(Check(ctx, c, closed)).To(Succeed())
+		err := Check(ctx, c, newest)
+		Expect(err).To(MatchError("denied by policy: payments allows 2 open issues per namespace and 2 are open"))
+
+		By("counting new issues after the existing ones")
+		Expect(Check(ctx, c, newIssue("new", "https://github.com/acme/payments-api", time.Time{}))).ToNot(Succeed())
+	})
+})
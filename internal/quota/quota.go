@@ -0,0 +1,328 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota limits how many GitHub issues the operator creates, so that a generator stuck in a loop cannot
+// flood repositories with issues
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultWindow is the window creates are counted over when the Limiter does not set one
+const DefaultWindow = time.Hour
+
+// stateKey is the key of the ConfigMap data the counted creates are stored under
+const stateKey = "state.json"
+
+// Scopes of an ExceededError
+const (
+	ScopeNamespace = "namespace"
+	ScopeRepo      = "repo"
+	ScopeBreaker   = "breaker"
+)
+
+// ExceededError is returned for creates over a quota, or while the circuit breaker is open
+type ExceededError struct {
+	//Scope is the quota that was exceeded, one of the Scope constants
+	Scope string
+	//Key is the namespace or the owner/repo over its quota, empty for the circuit breaker
+	Key   string
+	Limit int
+	//RetryAt is when the window moves on enough for a create to be allowed again
+	RetryAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	if e.Scope == ScopeBreaker {
+		return fmt.Sprintf("circuit breaker is open after %d issues were created across the cluster, creates are stopped until it is reset or the window expires", e.Limit)
+	}
+	return fmt.Sprintf("%s %s reached its quota of %d created issues", e.Scope, e.Key, e.Limit)
+}
+
+// Status is a snapshot of the creates counted by a Limiter
+type Status struct {
+	Tripped bool `json:"tripped"`
+	//TrippedUntil is when the open circuit breaker closes by itself, in RFC 3339
+	TrippedUntil string         `json:"trippedUntil,omitempty"`
+	Creates      int            `json:"creates"`
+	Namespaces   map[string]int `json:"namespaces"`
+	Repos        map[string]int `json:"repos"`
+}
+
+// Limiter counts the issues created over a sliding window, per namespace, per repo and across the cluster.
+// When the creates across the cluster reach BreakerThreshold the circuit breaker opens, and stops every create
+// for a whole window or until Reset is called. With a Client the counts and the circuit breaker are stored in
+// the ConfigMap, so that every shard counts against the same quotas, a reset reaches all of them and a restart
+// keeps an open circuit breaker. Without one they are kept in memory.
+// A nil Limiter allows every create
+type Limiter struct {
+	//PerNamespace is the number of creates allowed per namespace in a window, 0 means no limit
+	PerNamespace int
+	//PerRepo is the number of creates allowed per owner/repo in a window, 0 means no limit
+	PerRepo int
+	//BreakerThreshold is the number of creates across the cluster in a window that opens the circuit breaker, 0 disables it
+	BreakerThreshold int
+	//Window creates are counted over, DefaultWindow when zero
+	Window time.Duration
+	//Client stores the counted creates in the ConfigMap, nil keeps them in memory. It should not be a cached client
+	Client client.Client
+	//ConfigMap the counted creates are stored in, created on the first create
+	ConfigMap types.NamespacedName
+
+	mu    sync.Mutex
+	now   func() time.Time
+	state state
+}
+
+// state is the creates counted in the window and when the circuit breaker opened
+type state struct {
+	Namespaces map[string][]time.Time `json:"namespaces,omitempty"`
+	Repos      map[string][]time.Time `json:"repos,omitempty"`
+	Creates    []time.Time            `json:"creates,omitempty"`
+	TrippedAt  *time.Time             `json:"trippedAt,omitempty"`
+}
+
+// Acquire counts a create of an issue of the namespace in the owner/repo, or returns an *ExceededError without
+// counting it when a quota does not allow it. Creates that then fail are given back with Release
+func (l *Limiter) Acquire(ctx context.Context, namespace string, repo string) error {
+	if l == nil {
+		return nil
+	}
+	var exceeded *ExceededError
+	err := l.update(ctx, func(s *state) bool {
+		now, window := l.clock(), l.window()
+		s.prune(now, window)
+		exceeded = nil
+
+		if s.TrippedAt != nil {
+			exceeded = &ExceededError{Scope: ScopeBreaker, Limit: l.BreakerThreshold, RetryAt: s.TrippedAt.Add(window)}
+			return false
+		}
+		if l.PerNamespace > 0 && len(s.Namespaces[namespace]) >= l.PerNamespace {
+			exceeded = &ExceededError{Scope: ScopeNamespace, Key: namespace, Limit: l.PerNamespace, RetryAt: s.Namespaces[namespace][0].Add(window)}
+			return false
+		}
+		if l.PerRepo > 0 && len(s.Repos[repo]) >= l.PerRepo {
+			exceeded = &ExceededError{Scope: ScopeRepo, Key: repo, Limit: l.PerRepo, RetryAt: s.Repos[repo][0].Add(window)}
+			return false
+		}
+		if l.BreakerThreshold > 0 && len(s.Creates) >= l.BreakerThreshold {
+			s.TrippedAt = &now
+			exceeded = &ExceededError{Scope: ScopeBreaker, Limit: l.BreakerThreshold, RetryAt: now.Add(window)}
+			return true
+		}
+
+		if s.Namespaces == nil {
+			s.Namespaces, s.Repos = map[string][]time.Time{}, map[string][]time.Time{}
+		}
+		s.Namespaces[namespace] = append(s.Namespaces[namespace], now)
+		s.Repos[repo] = append(s.Repos[repo], now)
+		s.Creates = append(s.Creates, now)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed counting create: %v", err.Error())
+	}
+	if exceeded != nil {
+		return exceeded
+	}
+	return nil
+}
+
+// Release gives back the last create acquired for the namespace and owner/repo
+func (l *Limiter) Release(ctx context.Context, namespace string, repo string) error {
+	if l == nil {
+		return nil
+	}
+	err := l.update(ctx, func(s *state) bool {
+		if s.Namespaces == nil {
+			//Reset since the create was acquired
+			return false
+		}
+		s.Namespaces[namespace] = dropLast(s.Namespaces[namespace])
+		s.Repos[repo] = dropLast(s.Repos[repo])
+		s.Creates = dropLast(s.Creates)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed releasing create: %v", err.Error())
+	}
+	return nil
+}
+
+// Reset closes the circuit breaker and forgets every counted create
+func (l *Limiter) Reset(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	err := l.update(ctx, func(s *state) bool {
+		*s = state{}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed resetting quotas: %v", err.Error())
+	}
+	return nil
+}
+
+// Status returns the creates counted in the current window and the state of the circuit breaker
+func (l *Limiter) Status(ctx context.Context) (Status, error) {
+	status := Status{Namespaces: map[string]int{}, Repos: map[string]int{}}
+	if l == nil {
+		return status, nil
+	}
+	var s state
+	err := l.update(ctx, func(current *state) bool {
+		s = *current
+		return false
+	})
+	if err != nil {
+		return status, fmt.Errorf("failed reading quotas: %v", err.Error())
+	}
+	s.prune(l.clock(), l.window())
+	if s.TrippedAt != nil {
+		status.Tripped = true
+		status.TrippedUntil = s.TrippedAt.Add(l.window()).UTC().Format(time.RFC3339)
+	}
+	status.Creates = len(s.Creates)
+	for namespace, creates := range s.Namespaces {
+		status.Namespaces[namespace] = len(creates)
+	}
+	for repo, creates := range s.Repos {
+		status.Repos[repo] = len(creates)
+	}
+	return status, nil
+}
+
+// update runs change on the counted creates, and stores them when change returns true. With a Client the
+// counts are read from the ConfigMap, and change runs again when another shard updated it in between
+func (l *Limiter) update(ctx context.Context, change func(s *state) bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Client == nil {
+		current := l.state.clone()
+		if change(&current) {
+			l.state = current
+		}
+		return nil
+	}
+	conflict := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) }
+	return retry.OnError(retry.DefaultRetry, conflict, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := l.Client.Get(ctx, l.ConfigMap, configMap)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+		current := state{}
+		if data := configMap.Data[stateKey]; data != "" {
+			if err := json.Unmarshal([]byte(data), &current); err != nil {
+				return fmt.Errorf("invalid %s in configmap %s: %v", stateKey, l.ConfigMap, err.Error())
+			}
+		}
+		if !change(&current) {
+			return nil
+		}
+		data, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: l.ConfigMap.Name, Namespace: l.ConfigMap.Namespace}}
+		}
+		configMap.Data = map[string]string{stateKey: string(data)}
+		if !exists {
+			return l.Client.Create(ctx, configMap)
+		}
+		return l.Client.Update(ctx, configMap)
+	})
+}
+
+// prune drops the creates that left the window, and closes the circuit breaker once it was open for a whole window
+func (s *state) prune(now time.Time, window time.Duration) {
+	since := now.Add(-window)
+	if s.TrippedAt != nil && !s.TrippedAt.After(since) {
+		s.TrippedAt = nil
+	}
+	s.Creates = dropBefore(s.Creates, since)
+	for namespace, creates := range s.Namespaces {
+		if s.Namespaces[namespace] = dropBefore(creates, since); len(s.Namespaces[namespace]) == 0 {
+			delete(s.Namespaces, namespace)
+		}
+	}
+	for repo, creates := range s.Repos {
+		if s.Repos[repo] = dropBefore(creates, since); len(s.Repos[repo]) == 0 {
+			delete(s.Repos, repo)
+		}
+	}
+}
+
+// clone copies the counted creates, so that a change that is not stored leaves them as they were
+func (s state) clone() state {
+	cloned := state{Creates: append([]time.Time(nil), s.Creates...), TrippedAt: s.TrippedAt}
+	if s.Namespaces != nil {
+		cloned.Namespaces, cloned.Repos = map[string][]time.Time{}, map[string][]time.Time{}
+		for namespace, creates := range s.Namespaces {
+			cloned.Namespaces[namespace] = append([]time.Time(nil), creates...)
+		}
+		for repo, creates := range s.Repos {
+			cloned.Repos[repo] = append([]time.Time(nil), creates...)
+		}
+	}
+	return cloned
+}
+
+func (l *Limiter) window() time.Duration {
+	if l.Window <= 0 {
+		return DefaultWindow
+	}
+	return l.Window
+}
+
+func (l *Limiter) clock() time.Time {
+	if l.now == nil {
+		return time.Now()
+	}
+	return l.now()
+}
+
+// dropBefore drops the leading times that are not after since, times are in ascending order
+func dropBefore(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	return times[i:]
+}
+
+func dropLast(times []time.Time) []time.Time {
+	if len(times) == 0 {
+		return times
+	}
+	return times[:len(times)-1]
+}
@@ -0,0 +1,118 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Create quotas", func() {
+	ctx := context.Background()
+	var limiter *Limiter
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		limiter = &Limiter{PerNamespace: 2, PerRepo: 3, BreakerThreshold: 4, Window: time.Hour}
+		limiter.now = func() time.Time { return now }
+	})
+
+	status := func(l *Limiter) Status {
+		current, err := l.Status(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return current
+	}
+
+	exceeded := func(err error) *ExceededError {
+		exceededErr := &ExceededError{}
+		Expect(errors.As(err, &exceededErr)).To(BeTrue())
+		return exceededErr
+	}
+
+	It("allows every create when nil", func() {
+		var unlimited *Limiter
+		Expect(unlimited.Acquire(ctx, "default", "test/test")).To(Succeed())
+		Expect(unlimited.Release(ctx, "default", "test/test")).To(Succeed())
+		Expect(status(unlimited).Creates).To(BeZero())
+	})
+
+	It("limits the creates of a namespace and of a repo over the window", func() {
+		Expect(limiter.Acquire(ctx, "team-a", "test/one")).To(Succeed())
+		now = now.Add(10 * time.Minute)
+		Expect(limiter.Acquire(ctx, "team-a", "test/one")).To(Succeed())
+		err := exceeded(limiter.Acquire(ctx, "team-a", "test/two"))
+		Expect(err.Scope).To(Equal(ScopeNamespace))
+		Expect(err.RetryAt).To(Equal(now.Add(50 * time.Minute)))
+
+		Expect(limiter.Acquire(ctx, "team-b", "test/one")).To(Succeed())
+		Expect(exceeded(limiter.Acquire(ctx, "team-c", "test/one")).Scope).To(Equal(ScopeRepo))
+
+		By("counting the creates given back")
+		Expect(limiter.Release(ctx, "team-b", "test/one")).To(Succeed())
+		Expect(limiter.Acquire(ctx, "team-c", "test/one")).To(Succeed())
+
+		By("allowing creates once the window moved on")
+		now = now.Add(51 * time.Minute)
+		Expect(limiter.Acquire(ctx, "team-a", "test/two")).To(Succeed())
+		Expect(status(limiter).Namespaces).To(Equal(map[string]int{"team-a": 2, "team-c": 1}))
+	})
+
+	It("stops every create once the circuit breaker opens", func() {
+		for _, namespace := range []string{"a", "b", "c", "d"} {
+			Expect(limiter.Acquire(ctx, namespace, "test/"+namespace)).To(Succeed())
+		}
+		Expect(exceeded(limiter.Acquire(ctx, "e", "test/e")).Scope).To(Equal(ScopeBreaker))
+		Expect(status(limiter).Tripped).To(BeTrue())
+
+		By("staying open for a whole window after it opened")
+		now = now.Add(59 * time.Minute)
+		Expect(exceeded(limiter.Acquire(ctx, "e", "test/e")).Scope).To(Equal(ScopeBreaker))
+		now = now.Add(time.Minute)
+		Expect(limiter.Acquire(ctx, "e", "test/e")).To(Succeed())
+
+		By("closing when reset")
+		for _, namespace := range []string{"f", "g", "h", "i"} {
+			_ = limiter.Acquire(ctx, namespace, "test/"+namespace)
+		}
+		Expect(status(limiter).Tripped).To(BeTrue())
+		Expect(limiter.Reset(ctx)).To(Succeed())
+		Expect(status(limiter)).To(Equal(Status{Namespaces: map[string]int{}, Repos: map[string]int{}}))
+		Expect(limiter.Acquire(ctx, "j", "test/j")).To(Succeed())
+	})
+
+	It("shares the counts and the circuit breaker of the ConfigMap between limiters", func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		k8sClient := fake.NewClientBuilder().WithScheme(s).Build()
+		key := types.NamespacedName{Namespace: "githubissue-system", Name: "githubissue-create-quota"}
+		shared := func() *Limiter {
+			shard := &Limiter{PerNamespace: 2, BreakerThreshold: 3, Window: time.Hour, Client: k8sClient, ConfigMap: key}
+			shard.now = func() time.Time { return now }
+			return shard
+		}
+		first, second := shared(), shared()
+
+		Expect(first.Acquire(ctx, "team-a", "test/one")).To(Succeed())
+		Expect(second.Acquire(ctx, "team-a", "test/two")).To(Succeed())
+		Expect(exceeded(first.Acquire(ctx, "team-a", "test/three")).Scope).To(Equal(ScopeNamespace))
+		Expect(first.Acquire(ctx, "team-b", "test/one")).To(Succeed())
+		Expect(exceeded(second.Acquire(ctx, "team-c", "test/one")).Scope).To(Equal(ScopeBreaker))
+
+		By("keeping the open circuit breaker across restarts")
+		Expect(status(shared()).Tripped).To(BeTrue())
+		Expect(exceeded(shared().Acquire(ctx, "team-c", "test/one")).Scope).To(Equal(ScopeBreaker))
+
+		By("resetting every limiter")
+		Expect(first.Reset(ctx)).To(Succeed())
+		Expect(status(second)).To(Equal(Status{Namespaces: map[string]int{}, Repos: map[string]int{}}))
+		Expect(second.Acquire(ctx, "team-c", "test/one")).To(Succeed())
+		Expect(status(first).Creates).To(Equal(1))
+	})
+})
@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render renders the Go templates used in GithubIssue titles and descriptions
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	sprig "github.com/go-task/slim-sprig"
+)
+
+// Error is returned when a template cannot be parsed or executed
+type Error struct {
+	Name string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed rendering %s: %v", e.Name, e.Err.Error())
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FuncMap returns the sprig helpers available to templates.
+// Helpers reading the environment are removed, the manager environment holds the GitHub token.
+func FuncMap() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "env")
+	delete(funcs, "expandenv")
+	return funcs
+}
+
+// Render executes text as a Go template against data.
+// Objects omit empty fields, so missing keys render as "<no value>" rather than failing, the default helper picks a fallback
+func Render(name string, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(FuncMap()).Parse(text)
+	if err != nil {
+		return "", &Error{Name: name, Err: err}
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", &Error{Name: name, Err: err}
+	}
+	return out.String(), nil
+}
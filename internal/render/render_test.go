@@ -0,0 +1,51 @@
+package render
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Templates", func() {
+	DescribeTable("the helpers",
+		func(name string, available bool) {
+			_, ok := FuncMap()[name]
+			Expect(ok).To(Equal(available))
+		},
+		Entry("keep default", "default", true),
+		Entry("keep upper", "upper", true),
+		Entry("drop env", "env", false),
+		Entry("drop expandenv", "expandenv", false),
+	)
+
+	DescribeTable("rendering",
+		func(text string, data interface{}, rendered string) {
+			Expect(Render("title", text, data)).To(Equal(rendered))
+		},
+		Entry("a field", "{{ .Deployment.metadata.name }} is down",
+			map[string]interface{}{"Deployment": map[string]interface{}{"metadata": map[string]interface{}{"name": "web"}}},
+			"web is down"),
+		Entry("a missing key", "{{ .Deployment.metadata.annotations.owner }}",
+			map[string]interface{}{"Deployment": map[string]interface{}{"metadata": map[string]interface{}{}}},
+			"<no value>"),
+		Entry("a missing key with a default", `{{ .Deployment.metadata.annotations.owner | default "nobody" }}`,
+			map[string]interface{}{"Deployment": map[string]interface{}{"metadata": map[string]interface{}{}}},
+			"nobody"),
+		Entry("a sprig helper", "{{ .Name | upper }}", map[string]interface{}{"Name": "web"}, "WEB"),
+	)
+
+	DescribeTable("failing",
+		func(text string, message string) {
+			_, err := Render("description", text, map[string]interface{}{"Name": "web"})
+			var renderErr *Error
+			Expect(errors.As(err, &renderErr)).To(BeTrue())
+			Expect(renderErr.Name).To(Equal("description"))
+			Expect(errors.Unwrap(err)).To(Equal(renderErr.Err))
+			Expect(err).To(MatchError(And(HavePrefix("failed rendering description: "), ContainSubstring(message))))
+		},
+		Entry("to parse", "{{ .Name ", "unclosed action"),
+		Entry("to call a removed helper", `{{ env "GITHUB_TOKEN" }}`, `function "env" not defined`),
+		Entry("to execute", "{{ .Name.first }}", "can't evaluate field first"),
+	)
+})
@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package repourl parses the GitHub repository urls used in GithubIssue specs
+package repourl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Parse splits a repository url such as https://github.com/owner/repo into its owner and repository name
+func Parse(repoURL string) (owner string, repo string, err error) {
+	_, owner, repo, err = Split(repoURL)
+	return owner, repo, err
+}
+
+// Split splits a repository url such as https://github.com/owner/repo into its host, owner and repository name
+func Split(repoURL string) (host string, owner string, repo string, err error) {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid repository url %q: %v", repoURL, err.Error())
+	}
+	if parsed.Host == "" {
+		return "", "", "", fmt.Errorf("invalid repository url %q: missing host", repoURL)
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+		return "", "", "", fmt.Errorf("invalid repository url %q: expected <host>/<owner>/<repo>", repoURL)
+	}
+	return parsed.Host, segments[0], strings.TrimSuffix(segments[1], ".git"), nil
+}
+
+// Join builds the https url of a repository, the inverse of Split
+func Join(host string, owner string, repo string) string {
+	return fmt.Sprintf("https://%s/%s/%s", host, owner, repo)
+}
+
+// WebHost returns the host serving the repository urls of a GitHub REST API endpoint, github.com for api.github.com
+// and the host of the endpoint for GitHub Enterprise Server
+func WebHost(apiURL *url.URL) string {
+	if strings.EqualFold(apiURL.Hostname(), "api.github.com") {
+		return "github.com"
+	}
+	return apiURL.Host
+}
+
+// Expand turns an owner/repo shorthand into the url of a repository on github.com, urls are returned as is
+func Expand(repo string) string {
+	if strings.Contains(repo, "://") {
+		return repo
+	}
+	return "https://github.com/" + strings.Trim(strings.TrimSpace(repo), "/")
+}
+
+// Key returns a normalized owner/repo key, used to compare repository urls that point to the same repository
+func Key(repoURL string) (string, error) {
+	owner, repo, err := Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(owner + "/" + repo), nil
+}
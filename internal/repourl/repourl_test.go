@@ -0,0 +1,68 @@
+package repourl
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Repository urls", func() {
+	DescribeTable("splitting a url",
+		func(repoURL string, host string, owner string, repo string) {
+			gotHost, gotOwner, gotRepo, err := Split(repoURL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]string{gotHost, gotOwner, gotRepo}).To(Equal([]string{host, owner, repo}))
+		},
+		Entry("github.com", "https://github.com/owner/repo", "github.com", "owner", "repo"),
+		Entry("a trailing slash", "https://github.com/owner/repo/", "github.com", "owner", "repo"),
+		Entry("a .git suffix", "https://github.com/owner/repo.git", "github.com", "owner", "repo"),
+		Entry("a path below the repo", "https://github.com/owner/repo/issues/1", "github.com", "owner", "repo"),
+		Entry("surrounding spaces", " https://github.com/owner/repo ", "github.com", "owner", "repo"),
+		Entry("an enterprise host", "https://ghe.example.com/owner/repo", "ghe.example.com", "owner", "repo"),
+	)
+
+	DescribeTable("rejecting a url",
+		func(repoURL string, message string) {
+			_, _, err := Parse(repoURL)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("without a host", "owner/repo", "missing host"),
+		Entry("without a repo", "https://github.com/owner", "expected <host>/<owner>/<repo>"),
+		Entry("with an empty owner", "https://github.com//repo", "expected <host>/<owner>/<repo>"),
+		Entry("that does not parse", "https://github.com/%zz/repo", "invalid repository url"),
+	)
+
+	DescribeTable("expanding a repo",
+		func(repo string, expanded string) {
+			Expect(Expand(repo)).To(Equal(expanded))
+		},
+		Entry("owner/repo", "owner/repo", "https://github.com/owner/repo"),
+		Entry("owner/repo with slashes", " /owner/repo/ ", "https://github.com/owner/repo"),
+		Entry("a url", "https://ghe.example.com/owner/repo", "https://ghe.example.com/owner/repo"),
+	)
+
+	DescribeTable("keying a url",
+		func(repoURL string, key string) {
+			Expect(Key(repoURL)).To(Equal(key))
+		},
+		Entry("lowercases the owner and repo", "https://github.com/Owner/Repo", "owner/repo"),
+		Entry("drops the .git suffix", "https://github.com/owner/repo.git", "owner/repo"),
+	)
+
+	DescribeTable("resolving the host of an api endpoint",
+		func(apiURL string, host string) {
+			parsed, err := url.Parse(apiURL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(WebHost(parsed)).To(Equal(host))
+		},
+		Entry("api.github.com", "https://api.github.com/", "github.com"),
+		Entry("an enterprise endpoint", "https://ghe.example.com/api/v3/", "ghe.example.com"),
+	)
+
+	It("joins the parts that Split returns", func() {
+		host, owner, repo, err := Split(Join("ghe.example.com", "owner", "repo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect([]string{host, owner, repo}).To(Equal([]string{"ghe.example.com", "owner", "repo"}))
+	})
+})
@@ -0,0 +1,226 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package safety screens the titles, bodies and comments of issues for credentials before they are sent to GitHub,
+// where they may be public
+package safety
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ActionRedact replaces the matching content before sending it
+	ActionRedact = "redact"
+
+	// ActionBlock refuses to send content that matches
+	ActionBlock = "block"
+)
+
+// EntropyRule is the name of the findings of the entropy check
+const EntropyRule = "high-entropy"
+
+// DefaultEntropyThreshold in bits per character is above English text, names and hex digests, and below most
+// random tokens of DefaultEntropyMinLength characters
+const DefaultEntropyThreshold = 4.3
+
+// DefaultEntropyMinLength is the length of the shortest token checked for entropy
+const DefaultEntropyMinLength = 32
+
+// Rule reports the content matching a regular expression
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultRules detect common tokens, private keys and kubeconfig credentials
+var DefaultRules = []Rule{
+	{Name: "github-token", Pattern: `\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`},
+	{Name: "aws-access-key", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "slack-token", Pattern: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`},
+	{Name: "bearer-token", Pattern: `(?i)\bbearer [A-Za-z0-9._~+/-]{20,}=*`},
+	{Name: "private-key", Pattern: `(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|$)`},
+	{Name: "kubeconfig-credential", Pattern: `(?m)^[ \t-]*(client-key-data|client-certificate-data|token|password|id-token|refresh-token):[ \t]*\S+`},
+}
+
+// entropyCandidate matches the tokens checked for entropy. Hyphens and slashes split tokens, so that the generated
+// names of Kubernetes objects and the paths of links are checked segment by segment
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+=_]+`)
+
+// BlockedError is returned for content blocked by the rules
+type BlockedError struct {
+	Rules []string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("content matches the safety rules %s", strings.Join(e.Rules, ", "))
+}
+
+// Finding is a range of content matching a rule
+type Finding struct {
+	Rule  string
+	Start int
+	End   int
+}
+
+type compiledRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Scanner screens content with rules and an entropy check. A nil Scanner lets everything through
+type Scanner struct {
+	//Action is ActionRedact or ActionBlock
+	Action string
+	//EntropyThreshold in bits per character above which tokens are reported, entropy is not checked when zero
+	EntropyThreshold float64
+	//EntropyMinLength of the tokens checked for entropy
+	EntropyMinLength int
+
+	rules []compiledRule
+}
+
+// NewScanner compiles the rules of a scanner
+func NewScanner(action string, rules []Rule, entropyThreshold float64) (*Scanner, error) {
+	if action != ActionRedact && action != ActionBlock {
+		return nil, fmt.Errorf("invalid action %q, expected %s or %s", action, ActionRedact, ActionBlock)
+	}
+	scanner := &Scanner{Action: action, EntropyThreshold: entropyThreshold, EntropyMinLength: DefaultEntropyMinLength}
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of rule %s: %v", rule.Name, err.Error())
+		}
+		scanner.rules = append(scanner.rules, compiledRule{name: rule.Name, pattern: pattern})
+	}
+	return scanner, nil
+}
+
+// LoadRules reads a YAML list of rules with a name and a pattern
+func LoadRules(path string) ([]Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading rules: %v", err.Error())
+	}
+	var rules []Rule
+	if err := yaml.UnmarshalStrict(raw, &rules); err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %v", path, err.Error())
+	}
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("invalid rules in %s: every rule needs a name and a pattern", path)
+		}
+	}
+	return rules, nil
+}
+
+// Scan returns the findings in the content, ordered by position
+func (s *Scanner) Scan(content string) []Finding {
+	var findings []Finding
+	for _, rule := range s.rules {
+		for _, match := range rule.pattern.FindAllStringIndex(content, -1) {
+			findings = append(findings, Finding{Rule: rule.name, Start: match[0], End: match[1]})
+		}
+	}
+	if s.EntropyThreshold > 0 {
+		for _, match := range entropyCandidate.FindAllStringIndex(content, -1) {
+			token := content[match[0]:match[1]]
+			if len(token) >= s.EntropyMinLength && entropy(token) >= s.EntropyThreshold {
+				findings = append(findings, Finding{Rule: EntropyRule, Start: match[0], End: match[1]})
+			}
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Start != findings[j].Start {
+			return findings[i].Start < findings[j].Start
+		}
+		return findings[i].End > findings[j].End
+	})
+	return findings
+}
+
+// Redact replaces the findings in the content with the name of their rule
+func Redact(content string, findings []Finding) string {
+	var redacted strings.Builder
+	end := 0
+	for _, finding := range findings {
+		if finding.End <= end {
+			//Within a finding already redacted
+			continue
+		}
+		if finding.Start >= end {
+			redacted.WriteString(content[end:finding.Start])
+			fmt.Fprintf(&redacted, "[REDACTED:%s]", finding.Rule)
+		}
+		end = finding.End
+	}
+	redacted.WriteString(content[end:])
+	return redacted.String()
+}
+
+// Screen redacts the content in place, or returns a BlockedError when blocking. It returns the rules that matched
+func (s *Scanner) Screen(contents ...*string) ([]string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	matched := map[string]bool{}
+	redacted := make([]string, len(contents))
+	for i, content := range contents {
+		findings := s.Scan(*content)
+		for _, finding := range findings {
+			matched[finding.Rule] = true
+		}
+		redacted[i] = Redact(*content, findings)
+	}
+	rules := make([]string, 0, len(matched))
+	for rule := range matched {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if s.Action == ActionBlock {
+		return rules, &BlockedError{Rules: rules}
+	}
+	for i, content := range contents {
+		*content = redacted[i]
+	}
+	return rules, nil
+}
+
+// entropy returns the Shannon entropy of the token in bits per character
+func entropy(token string) float64 {
+	counts := map[rune]int{}
+	for _, char := range token {
+		counts[char]++
+	}
+	total := float64(len(token))
+	bits := 0.0
+	for _, count := range counts {
+		p := float64(count) / total
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"dvir.io/githubissue/internal/quota"
+	"go.uber.org/zap"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// QuotaHandler shows and resets the create quotas of the Limiter. Callers authenticate with a Kubernetes
+// bearer token and must be allowed to get /quota, or to post /quota/reset, as non-resource urls
+type QuotaHandler struct {
+	Client  client.Client
+	Log     *zap.Logger
+	Limiter *quota.Limiter
+	//Elected is closed once this replica is the leader, only the leader creates issues and counts them
+	Elected <-chan struct{}
+}
+
+// ServeHTTP handles GET /quota, returning the quota.Status, and POST /quota/reset, closing the circuit breaker
+// and forgetting the counted creates
+func (h *QuotaHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	verb := map[string]string{"/quota": http.MethodGet, "/quota/reset": http.MethodPost}[req.URL.Path]
+	if verb == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != verb {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	select {
+	case <-h.Elected:
+	default:
+		http.Error(w, "this replica is not the leader, retry against the leader", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx := req.Context()
+	user, err := authenticate(ctx, h.Client, req)
+	if err != nil {
+		h.Log.Error("failed authenticating quota request", zap.Error(err))
+		http.Error(w, "authentication failed", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ok, err := allowed(ctx, h.Client, user, authorizationv1.SubjectAccessReviewSpec{
+		NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: req.URL.Path, Verb: map[string]string{http.MethodGet: "get", http.MethodPost: "post"}[verb]},
+	})
+	if err != nil {
+		h.Log.Error("failed authorizing quota request", zap.Error(err))
+		http.Error(w, "authorization failed", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not allowed to %s %s", user.Username, req.Method, req.URL.Path), http.StatusForbidden)
+		return
+	}
+
+	if verb == http.MethodPost {
+		if err := h.Limiter.Reset(ctx); err != nil {
+			h.Log.Error("failed resetting create quotas", zap.Error(err))
+			http.Error(w, "reset failed", http.StatusInternalServerError)
+			return
+		}
+		h.Log.Info("create quotas reset", zap.String("user", user.Username))
+	}
+	status, err := h.Limiter.Status(ctx)
+	if err != nil {
+		h.Log.Error("failed reading create quotas", zap.Error(err))
+		http.Error(w, "reading quotas failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
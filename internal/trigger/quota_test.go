@@ -0,0 +1,89 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"dvir.io/githubissue/internal/quota"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Quota endpoint", func() {
+	var (
+		handler *QuotaHandler
+		elected chan struct{}
+	)
+
+	call := func(method string, path string, token string) (int, *quota.Status) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		status := &quota.Status{}
+		Expect(json.NewDecoder(rec.Body).Decode(status)).To(Succeed())
+		return rec.Code, status
+	}
+
+	BeforeEach(func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		k8sClient := fake.NewClientBuilder().WithScheme(s).
+			WithInterceptorFuncs(interceptor.Funcs{Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				//The token is the user name, everyone may get /quota and only admin may reset it
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: review.Spec.Token}
+				case *authorizationv1.SubjectAccessReview:
+					attributes := review.Spec.NonResourceAttributes
+					review.Status.Allowed = (attributes.Path == "/quota" && attributes.Verb == "get") || review.Spec.User == "admin"
+				default:
+					return c.Create(ctx, obj, opts...)
+				}
+				return nil
+			}}).
+			Build()
+		limiter := &quota.Limiter{BreakerThreshold: 1}
+		Expect(limiter.Acquire(context.Background(), "default", "test/test")).To(Succeed())
+		Expect(limiter.Acquire(context.Background(), "default", "test/test")).ToNot(Succeed())
+		elected = make(chan struct{})
+		handler = &QuotaHandler{Client: k8sClient, Log: zap.NewNop(), Limiter: limiter, Elected: elected}
+	})
+
+	It("only answers on the leader", func() {
+		code, _ := call(http.MethodGet, "/quota", "viewer")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("shows the quotas and lets allowed users reset them", func() {
+		close(elected)
+		code, status := call(http.MethodGet, "/quota", "viewer")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(status.Tripped).To(BeTrue())
+		Expect(status.Namespaces).To(Equal(map[string]int{"default": 1}))
+
+		code, _ = call(http.MethodGet, "/quota/reset", "admin")
+		Expect(code).To(Equal(http.StatusMethodNotAllowed))
+		code, _ = call(http.MethodPost, "/quota/reset", "viewer")
+		Expect(code).To(Equal(http.StatusForbidden))
+
+		code, status = call(http.MethodPost, "/quota/reset", "admin")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(status.Tripped).To(BeFalse())
+		Expect(status.Creates).To(BeZero())
+	})
+})
@@ -0,0 +1,75 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// Server serves a Handler, it is added to the manager as a runnable
+type Server struct {
+	Addr string
+	//CertDir holds tls.crt and tls.key, the endpoint refuses to start without it unless Insecure is set
+	CertDir string
+	//Insecure serves the endpoint over plain http when CertDir is empty, exposing the bearer tokens of callers
+	Insecure bool
+	Handler  *Handler
+	//Quota serves the create quotas of the manager when not nil
+	Quota *QuotaHandler
+}
+
+// Start serves the handler until the context is done
+func (s *Server) Start(ctx context.Context) error {
+	if s.CertDir == "" && !s.Insecure {
+		return fmt.Errorf("refusing to serve the reconcile endpoint on %s without tls, callers send their bearer tokens", s.Addr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/reconcile", s.Handler)
+	if s.Quota != nil {
+		mux.Handle("/quota", s.Quota)
+		mux.Handle("/quota/reset", s.Quota)
+	}
+	server := &http.Server{Addr: s.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	s.Handler.Log.Info("serving reconcile endpoint on " + s.Addr)
+	var err error
+	if s.CertDir != "" {
+		err = server.ListenAndServeTLS(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	} else {
+		s.Handler.Log.Warn("serving the reconcile endpoint over plain http, bearer tokens of callers are sent in clear text")
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection is false, requests are recorded on the objects and picked up by the leader
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trigger serves an endpoint requesting the reconcile of GithubIssue objects on demand, so that incident
+// tooling does not have to wait for the next resync to see the state of an issue
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/repourl"
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Handler requests the reconcile of a GithubIssue, or of every GithubIssue of a repository, by setting their
+// issues.dvir.io/reconcile-requested-at annotation. Callers authenticate with a Kubernetes bearer token and
+// must be allowed to patch the githubissues they reconcile
+type Handler struct {
+	Client client.Client
+	Log    *zap.Logger
+}
+
+// Response lists the GithubIssue objects whose reconcile was requested
+type Response struct {
+	RequestedAt string   `json:"requestedAt"`
+	Reconciling []string `json:"reconciling"`
+}
+
+// ServeHTTP handles POST /reconcile?namespace=<namespace>&name=<name> and POST /reconcile?repo=<repo>[&namespace=<namespace>]
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+	user, err := authenticate(ctx, h.Client, req)
+	if err != nil {
+		h.Log.Error("failed authenticating reconcile request", zap.Error(err))
+		http.Error(w, "authentication failed", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := req.URL.Query()
+	namespace, name, repo := query.Get("namespace"), query.Get("name"), query.Get("repo")
+	var targets []types.NamespacedName
+	switch {
+	case name != "" && namespace != "":
+		targets = []types.NamespacedName{{Namespace: namespace, Name: name}}
+	case repo != "":
+		targets, err = h.issuesOf(ctx, repo, namespace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "expected namespace and name, or repo", http.StatusBadRequest)
+		return
+	}
+
+	allowed := map[string]bool{}
+	response := &Response{RequestedAt: time.Now().UTC().Format(time.RFC3339Nano), Reconciling: []string{}}
+	denied := 0
+	for _, target := range targets {
+		//A single object is authorized by name, a repository by namespace
+		resourceName := ""
+		if repo == "" {
+			resourceName = target.Name
+		}
+		ok, known := allowed[target.Namespace]
+		if !known || resourceName != "" {
+			ok, err = h.authorize(ctx, user, target.Namespace, resourceName)
+			if err != nil {
+				h.Log.Error("failed authorizing reconcile request", zap.Error(err))
+				http.Error(w, "authorization failed", http.StatusInternalServerError)
+				return
+			}
+			allowed[target.Namespace] = ok
+		}
+		if !ok {
+			denied++
+			continue
+		}
+		if err := h.request(ctx, target, response.RequestedAt); err != nil {
+			if k8serrors.IsNotFound(err) {
+				http.Error(w, fmt.Sprintf("githubissue %s not found", target), http.StatusNotFound)
+				return
+			}
+			h.Log.Error("failed requesting reconcile", zap.String("issue", target.String()), zap.Error(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.Reconciling = append(response.Reconciling, target.String())
+	}
+	if denied > 0 && len(response.Reconciling) == 0 {
+		http.Error(w, fmt.Sprintf("%s is not allowed to patch githubissues", user.Username), http.StatusForbidden)
+		return
+	}
+	h.Log.Info("reconcile requested", zap.String("user", user.Username), zap.Strings("issues", response.Reconciling))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// authenticate reviews the bearer token of the request, returning nil when it is missing or invalid
+func authenticate(ctx context.Context, c client.Client, req *http.Request) (*authenticationv1.UserInfo, error) {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, nil
+	}
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := c.Create(ctx, review); err != nil {
+		return nil, fmt.Errorf("failed reviewing token: %v", err.Error())
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+	return &review.Status.User, nil
+}
+
+// authorize checks that the user may patch the named githubissue, or every githubissue of the namespace when name is empty
+func (h *Handler) authorize(ctx context.Context, user *authenticationv1.UserInfo, namespace string, name string) (bool, error) {
+	return allowed(ctx, h.Client, user, authorizationv1.SubjectAccessReviewSpec{
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      "patch",
+			Group:     issuesv1.GroupVersion.Group,
+			Resource:  "githubissues",
+			Name:      name,
+		},
+	})
+}
+
+// allowed reviews the access of the user to the attributes of the spec
+func allowed(ctx context.Context, c client.Client, user *authenticationv1.UserInfo, spec authorizationv1.SubjectAccessReviewSpec) (bool, error) {
+	spec.User, spec.UID, spec.Groups = user.Username, user.UID, user.Groups
+	spec.Extra = map[string]authorizationv1.ExtraValue{}
+	for key, values := range user.Extra {
+		spec.Extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review := &authorizationv1.SubjectAccessReview{Spec: spec}
+	if err := c.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed reviewing access: %v", err.Error())
+	}
+	return review.Status.Allowed, nil
+}
+
+// issuesOf lists the GithubIssue objects filed in a repository, in a namespace or in every namespace when empty
+func (h *Handler) issuesOf(ctx context.Context, repo string, namespace string) ([]types.NamespacedName, error) {
+	key, err := repourl.Key(repourl.Expand(repo))
+	if err != nil {
+		return nil, err
+	}
+	issues := &issuesv1.GithubIssueList{}
+	if err := h.Client.List(ctx, issues, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed listing githubissues: %v", err.Error())
+	}
+	var targets []types.NamespacedName
+	for _, issue := range issues.Items {
+		if issueKey, err := repourl.Key(issue.Spec.Repo); err == nil && issueKey == key {
+			targets = append(targets, client.ObjectKeyFromObject(&issue))
+		}
+	}
+	return targets, nil
+}
+
+// request sets the reconcile requested annotation of a GithubIssue
+func (h *Handler) request(ctx context.Context, target types.NamespacedName, requestedAt string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{
+			issuesv1.ReconcileRequestedAnnotation: requestedAt,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	issue := &issuesv1.GithubIssue{ObjectMeta: metav1.ObjectMeta{Namespace: target.Namespace, Name: target.Name}}
+	return h.Client.Patch(ctx, issue, client.RawPatch(types.MergePatchType, patch))
+}
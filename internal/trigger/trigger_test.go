@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Reconcile endpoint", func() {
+	var (
+		k8sClient client.Client
+		handler   *Handler
+		ctx       = context.Background()
+	)
+
+	issue := func(namespace string, name string, repo string) *issuesv1.GithubIssue {
+		return &issuesv1.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       issuesv1.GithubIssueSpec{Repo: repo, Title: name},
+		}
+	}
+
+	post := func(query string, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/reconcile?"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	requested := func(namespace string, name string) bool {
+		reconciled := &issuesv1.GithubIssue{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, reconciled)).To(Succeed())
+		_, ok := reconciled.Annotations[issuesv1.ReconcileRequestedAnnotation]
+		return ok
+	}
+
+	BeforeEach(func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(issuesv1.AddToScheme(s)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(s).
+			WithObjects(
+				issue("team-a", "latency", "https://github.com/test/test"),
+				issue("team-a", "errors", "https://github.com/test/other"),
+				issue("team-b", "disk", "https://github.com/Test/test"),
+			).
+			WithInterceptorFuncs(interceptor.Funcs{Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				//The token is the user name, users may patch githubissues in team-a only
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					review.Status.Authenticated = review.Spec.Token != "invalid"
+					review.Status.User = authenticationv1.UserInfo{Username: review.Spec.Token}
+				case *authorizationv1.SubjectAccessReview:
+					attributes := review.Spec.ResourceAttributes
+					review.Status.Allowed = attributes.Verb == "patch" && attributes.Resource == "githubissues" &&
+						(review.Spec.User == "admin" || attributes.Namespace == "team-a")
+				default:
+					return c.Create(ctx, obj, opts...)
+				}
+				return nil
+			}}).
+			Build()
+		handler = &Handler{Client: k8sClient, Log: zap.NewNop()}
+	})
+
+	It("rejects requests without a valid token", func() {
+		Expect(post("namespace=team-a&name=latency", "")).To(Equal(http.StatusUnauthorized))
+		Expect(post("namespace=team-a&name=latency", "invalid")).To(Equal(http.StatusUnauthorized))
+		Expect(requested("team-a", "latency")).To(BeFalse())
+	})
+
+	It("requests the reconcile of a single object the caller may patch", func() {
+		Expect(post("namespace=team-a&name=latency", "oncall")).To(Equal(http.StatusOK))
+		Expect(requested("team-a", "latency")).To(BeTrue())
+		Expect(requested("team-a", "errors")).To(BeFalse())
+
+		Expect(post("namespace=team-b&name=disk", "oncall")).To(Equal(http.StatusForbidden))
+		Expect(requested("team-b", "disk")).To(BeFalse())
+		Expect(post("namespace=team-a&name=missing", "oncall")).To(Equal(http.StatusNotFound))
+	})
+
+	It("requests the reconcile of every object of a repository in the namespaces the caller may patch", func() {
+		Expect(post("repo=test/test", "oncall")).To(Equal(http.StatusOK))
+		Expect(requested("team-a", "latency")).To(BeTrue())
+		Expect(requested("team-a", "errors")).To(BeFalse())
+		Expect(requested("team-b", "disk")).To(BeFalse())
+
+		Expect(post("repo=https://github.com/test/test", "admin")).To(Equal(http.StatusOK))
+		Expect(requested("team-b", "disk")).To(BeTrue())
+	})
+
+	It("rejects malformed requests", func() {
+		Expect(post("namespace=team-a", "oncall")).To(Equal(http.StatusBadRequest))
+		req := httptest.NewRequest(http.MethodGet, "/reconcile?namespace=team-a&name=latency", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("refuses to serve without tls unless insecure", func() {
+		server := &Server{Addr: "127.0.0.1:0", Handler: handler}
+		Expect(server.Start(ctx)).To(MatchError(ContainSubstring("without tls")))
+
+		serveCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		server.Insecure = true
+		Expect(server.Start(serveCtx)).To(Succeed())
+	})
+})
@@ -0,0 +1,606 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/policy"
+	"dvir.io/githubissue/internal/repourl"
+	"github.com/google/go-github/v56/github"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var githubissuelog = logf.Log.WithName("githubissue-resource")
+
+const maxLabelLength = 50
+
+// assigneePattern matches GitHub logins: alphanumerics and single hyphens, not starting or ending with a hyphen
+var assigneePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+// SetupGithubIssueWebhookWithManager registers the GithubIssue defaulting and validating webhooks with the manager.
+// When gitHubClient is not nil, the repo of new issues is checked to exist and to be writable with the token.
+// clusterName is exposed to the body footer templates of GithubIssueDefaults.
+func SetupGithubIssueWebhookWithManager(mgr ctrl.Manager, gitHubClient *github.Client, clusterName string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&issuesv1.GithubIssue{}).
+		WithDefaulter(&GithubIssueCustomDefaulter{Client: mgr.GetClient(), ClusterName: clusterName}).
+		WithValidator(&GithubIssueCustomValidator{Client: mgr.GetClient(), RESTMapper: mgr.GetRESTMapper(), GitHubClient: gitHubClient}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-issues-dvir-io-v1-githubissue,mutating=true,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githubissues,verbs=create;update,versions=v1,name=mgithubissue.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuedefaults,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// GithubIssueCustomDefaulter injects the conventions of matching GithubIssueDefaults into GithubIssue objects
+type GithubIssueCustomDefaulter struct {
+	Client      client.Reader
+	ClusterName string
+}
+
+var _ webhook.CustomDefaulter = &GithubIssueCustomDefaulter{}
+
+// footerData is the data available to GithubIssueDefaults body footer templates
+type footerData struct {
+	Cluster   string
+	Namespace string
+	Name      string
+}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *GithubIssueCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	issue, ok := obj.(*issuesv1.GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a GithubIssue object but got %T", obj)
+	}
+	githubissuelog.Info("default", "name", issue.Name)
+
+	if !issue.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	matching, err := d.matchingDefaults(ctx, issue)
+	if err != nil {
+		return err
+	}
+
+	titlePrefixSet, footerSet := false, false
+	for _, defaults := range matching {
+		if issue.Spec.SyncInterval == nil && defaults.Spec.SyncInterval != nil {
+			issue.Spec.SyncInterval = defaults.Spec.SyncInterval.DeepCopy()
+		}
+		issue.Spec.Labels = appendMissing(issue.Spec.Labels, defaults.Spec.Labels)
+		issue.Spec.Assignees = appendMissing(issue.Spec.Assignees, defaults.Spec.Assignees)
+		if !titlePrefixSet && defaults.Spec.TitlePrefix != "" {
+			titlePrefixSet = true
+			if !strings.HasPrefix(issue.Spec.Title, defaults.Spec.TitlePrefix) {
+				issue.Spec.Title = defaults.Spec.TitlePrefix + issue.Spec.Title
+			}
+		}
+		if !footerSet && defaults.Spec.BodyFooter != "" {
+			footerSet = true
+			footer, err := renderFooter(defaults, footerData{Cluster: d.ClusterName, Namespace: issue.Namespace, Name: issue.Name})
+			if err != nil {
+				return err
+			}
+			if !strings.HasSuffix(issue.Spec.Description, footer) {
+				if issue.Spec.Description != "" {
+					issue.Spec.Description += "\n\n"
+				}
+				issue.Spec.Description += footer
+			}
+		}
+	}
+	return nil
+}
+
+// matchingDefaults returns the GithubIssueDefaults that apply to the issue, most specific first
+func (d *GithubIssueCustomDefaulter) matchingDefaults(ctx context.Context, issue *issuesv1.GithubIssue) ([]issuesv1.GithubIssueDefaults, error) {
+	defaultsList := &issuesv1.GithubIssueDefaultsList{}
+	if err := d.Client.List(ctx, defaultsList); err != nil {
+		return nil, fmt.Errorf("failed listing issue defaults: %v", err.Error())
+	}
+	if len(defaultsList.Items) == 0 {
+		return nil, nil
+	}
+	owner, repo, err := repourl.Parse(issue.Spec.Repo)
+	if err != nil {
+		// Let the validating webhook report the malformed repo
+		return nil, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := d.Client.Get(ctx, types.NamespacedName{Name: issue.Namespace}, namespace); err != nil {
+		return nil, fmt.Errorf("failed fetching namespace %s: %v", issue.Namespace, err.Error())
+	}
+
+	var matching []issuesv1.GithubIssueDefaults
+	specificity := map[string]int{}
+	for _, defaults := range defaultsList.Items {
+		score := 0
+		if defaults.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(defaults.Spec.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace selector in %s: %v", defaults.Name, err.Error())
+			}
+			if !selector.Matches(labels.Set(namespace.Labels)) {
+				continue
+			}
+			if !selector.Empty() {
+				score++
+			}
+		}
+		if len(defaults.Spec.RepoOwners) > 0 {
+			if !containsFold(defaults.Spec.RepoOwners, owner) {
+				continue
+			}
+			score += 2
+		}
+		if len(defaults.Spec.Repos) > 0 {
+			if !containsFold(defaults.Spec.Repos, owner+"/"+repo) {
+				continue
+			}
+			score += 4
+		}
+		specificity[defaults.Name] = score
+		matching = append(matching, defaults)
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if specificity[matching[i].Name] != specificity[matching[j].Name] {
+			return specificity[matching[i].Name] > specificity[matching[j].Name]
+		}
+		return matching[i].Name < matching[j].Name
+	})
+	return matching, nil
+}
+
+func renderFooter(defaults issuesv1.GithubIssueDefaults, data footerData) (string, error) {
+	footerTemplate, err := template.New(defaults.Name).Option("missingkey=error").Parse(defaults.Spec.BodyFooter)
+	if err != nil {
+		return "", fmt.Errorf("invalid body footer in %s: %v", defaults.Name, err.Error())
+	}
+	var footer bytes.Buffer
+	if err := footerTemplate.Execute(&footer, data); err != nil {
+		return "", fmt.Errorf("failed rendering body footer of %s: %v", defaults.Name, err.Error())
+	}
+	return footer.String(), nil
+}
+
+// appendMissing appends the values that are not already present, ignoring case
+func appendMissing(values []string, defaults []string) []string {
+	for _, value := range defaults {
+		if !containsFold(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//+kubebuilder:webhook:path=/validate-issues-dvir-io-v1-githubissue,mutating=false,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githubissues,verbs=create;update,versions=v1,name=vgithubissue.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// GithubIssueCustomValidator validates GithubIssue objects on create and update
+type GithubIssueCustomValidator struct {
+	Client client.Client
+	//RESTMapper resolves the resources of template inputs, their access is not reviewed when nil
+	RESTMapper   meta.RESTMapper
+	GitHubClient *github.Client
+}
+
+var _ webhook.CustomValidator = &GithubIssueCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	issue, ok := obj.(*issuesv1.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", obj)
+	}
+	githubissuelog.Info("validate create", "name", issue.Name)
+
+	allErrs := validateSpec(issue)
+	if len(allErrs) == 0 {
+		allErrs = append(allErrs, v.validateUnique(ctx, issue)...)
+	}
+	if len(allErrs) == 0 {
+		allErrs = append(allErrs, v.validatePolicy(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && issue.Spec.DescriptionFrom != nil {
+		allErrs = append(allErrs, v.validateDescriptionFrom(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && len(issue.Spec.TemplateInputs) > 0 {
+		allErrs = append(allErrs, v.validateTemplateInputs(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && len(issue.Spec.OnPullRequestMerged) > 0 {
+		allErrs = append(allErrs, v.validateActions(ctx, issue)...)
+	}
+	if len(allErrs) == 0 {
+		allErrs = append(allErrs, v.validateRepoAccess(ctx, issue)...)
+	}
+	return nil, toInvalid(issue, allErrs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldIssue, ok := oldObj.(*issuesv1.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", oldObj)
+	}
+	issue, ok := newObj.(*issuesv1.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", newObj)
+	}
+	githubissuelog.Info("validate update", "name", issue.Name)
+
+	// Objects being deleted only have their finalizers removed, there is nothing left to validate
+	if !issue.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	allErrs := validateSpec(issue)
+	allErrs = append(allErrs, validateBinding(oldIssue, issue)...)
+	// Only changes of the target are checked, so the controller can still update issues that a later object collides with
+	if len(allErrs) == 0 && (oldIssue.Spec.Repo != issue.Spec.Repo || oldIssue.Spec.Title != issue.Spec.Title || oldIssue.Spec.Number != issue.Spec.Number) {
+		allErrs = append(allErrs, v.validateUnique(ctx, issue)...)
+	}
+	// Metadata updates, such as the finalizers of the controller, are let through issues denied by a newer policy
+	if len(allErrs) == 0 && !equality.Semantic.DeepEqual(oldIssue.Spec, issue.Spec) {
+		allErrs = append(allErrs, v.validatePolicy(ctx, issue)...)
+	}
+	// The description source and template inputs are read and actions run with the permissions of the manager, so
+	// whoever adds or changes them must hold these permissions
+	if len(allErrs) == 0 && issue.Spec.DescriptionFrom != nil && !equality.Semantic.DeepEqual(oldIssue.Spec.DescriptionFrom, issue.Spec.DescriptionFrom) {
+		allErrs = append(allErrs, v.validateDescriptionFrom(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && !equality.Semantic.DeepEqual(oldIssue.Spec.TemplateInputs, issue.Spec.TemplateInputs) {
+		allErrs = append(allErrs, v.validateTemplateInputs(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && !equality.Semantic.DeepEqual(oldIssue.Spec.OnPullRequestMerged, issue.Spec.OnPullRequestMerged) {
+		allErrs = append(allErrs, v.validateActions(ctx, issue)...)
+	}
+	if len(allErrs) == 0 && oldIssue.Spec.Repo != issue.Spec.Repo {
+		allErrs = append(allErrs, v.validateRepoAccess(ctx, issue)...)
+	}
+	return nil, toInvalid(issue, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateSpec checks the formats of the repo, labels and assignees
+func validateSpec(issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, _, err := repourl.Parse(issue.Spec.Repo); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("repo"), issue.Spec.Repo, err.Error()))
+	}
+	if strings.TrimSpace(issue.Spec.Title) == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("title"), "title must not be blank"))
+	}
+
+	seenLabels := map[string]bool{}
+	for i, label := range issue.Spec.Labels {
+		labelPath := specPath.Child("labels").Index(i)
+		switch {
+		case strings.TrimSpace(label) == "":
+			allErrs = append(allErrs, field.Invalid(labelPath, label, "label must not be blank"))
+		case label != strings.TrimSpace(label):
+			allErrs = append(allErrs, field.Invalid(labelPath, label, "label must not start or end with whitespace"))
+		case len(label) > maxLabelLength:
+			allErrs = append(allErrs, field.TooLong(labelPath, label, maxLabelLength))
+		case seenLabels[strings.ToLower(label)]:
+			allErrs = append(allErrs, field.Duplicate(labelPath, label))
+		}
+		seenLabels[strings.ToLower(label)] = true
+	}
+
+	seenAssignees := map[string]bool{}
+	for i, assignee := range issue.Spec.Assignees {
+		assigneePath := specPath.Child("assignees").Index(i)
+		switch {
+		case !assigneePattern.MatchString(assignee):
+			allErrs = append(allErrs, field.Invalid(assigneePath, assignee, "must be a valid GitHub login"))
+		case seenAssignees[strings.ToLower(assignee)]:
+			allErrs = append(allErrs, field.Duplicate(assigneePath, assignee))
+		}
+		seenAssignees[strings.ToLower(assignee)] = true
+	}
+	return allErrs
+}
+
+// validateBinding rejects changes to the repo or the issue number once the object is bound to a GitHub issue
+func validateBinding(oldIssue *issuesv1.GithubIssue, issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	boundNumber := oldIssue.Status.Number
+	if boundNumber == 0 {
+		boundNumber = oldIssue.Spec.Number
+	}
+	if boundNumber == 0 {
+		return allErrs
+	}
+	oldKey, _ := repourl.Key(oldIssue.Spec.Repo)
+	newKey, _ := repourl.Key(issue.Spec.Repo)
+	if oldKey != newKey {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "repo"),
+			fmt.Sprintf("repo is immutable once bound to issue #%d", boundNumber)))
+	}
+	if issue.Spec.Number != 0 && issue.Spec.Number != boundNumber {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "number"),
+			fmt.Sprintf("number is immutable once bound to issue #%d", boundNumber)))
+	}
+	return allErrs
+}
+
+// validateUnique rejects objects that target the same issue as another GithubIssue in the cluster. Titles are only
+// compared between objects without spec.number, as objects bound by it, such as imported issues, may share a title
+// with another issue. An object found by its title, that only has status.number, still claims its title while its
+// issue is open, since another object with that title would find the same issue. Closed issues are not searched
+func (v *GithubIssueCustomValidator) validateUnique(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	key, err := repourl.Key(issue.Spec.Repo)
+	if err != nil {
+		return allErrs
+	}
+	issueList := &issuesv1.GithubIssueList{}
+	if err := v.Client.List(ctx, issueList); err != nil {
+		return append(allErrs, field.InternalError(field.NewPath("spec"), fmt.Errorf("failed listing issues: %v", err.Error())))
+	}
+	number := issue.Spec.Number
+	if number == 0 {
+		number = issue.Status.Number
+	}
+	for i := range issueList.Items {
+		other := &issueList.Items[i]
+		if other.Namespace == issue.Namespace && other.Name == issue.Name {
+			continue
+		}
+		if otherKey, err := repourl.Key(other.Spec.Repo); err != nil || otherKey != key {
+			continue
+		}
+		otherNumber := other.Spec.Number
+		if otherNumber == 0 {
+			otherNumber = other.Status.Number
+		}
+		if number != 0 && number == otherNumber {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec", "number"),
+				fmt.Sprintf("issue #%d is already managed by %s/%s", number, other.Namespace, other.Name)))
+		} else if issue.Spec.Number == 0 && other.Spec.Number == 0 && !meta.IsStatusConditionFalse(other.Status.Conditions, "IssueIsOpen") &&
+			strings.EqualFold(other.Spec.Title, issue.Spec.Title) {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec", "title"),
+				fmt.Sprintf("an issue with this title is already managed by %s/%s", other.Namespace, other.Name)))
+		}
+	}
+	return allErrs
+}
+
+// validatePolicy rejects issues that the GithubIssuePolicy objects selecting their namespace do not allow
+func (v *GithubIssueCustomValidator) validatePolicy(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	specPath := field.NewPath("spec")
+	return policyErrors(policy.Check(ctx, v.Client, issue), specPath, specPath.Child("repo"), specPath.Child("labels"))
+}
+
+// policyErrors turns the denial of an object by the GithubIssuePolicy objects into errors of the fields at fault.
+// Label violations are reported at their index of labelsPath
+func policyErrors(err error, specPath *field.Path, repoPath *field.Path, labelsPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	denied := &policy.DeniedError{}
+	if !errors.As(err, &denied) {
+		if err != nil {
+			allErrs = append(allErrs, field.InternalError(specPath, err))
+		}
+		return allErrs
+	}
+	for _, violation := range denied.Violations {
+		violationPath := specPath
+		switch violation.Field {
+		case policy.FieldRepo:
+			violationPath = repoPath
+		case policy.FieldLabels:
+			violationPath = labelsPath.Index(violation.Index)
+		}
+		allErrs = append(allErrs, field.Forbidden(violationPath, violation.Message))
+	}
+	return allErrs
+}
+
+// validateTemplateInputs rejects Secrets as template inputs, and inputs that the requesting user could not read themselves
+func (v *GithubIssueCustomValidator) validateTemplateInputs(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	inputsPath := field.NewPath("spec", "templateInputs")
+	for i, input := range issue.Spec.TemplateInputs {
+		inputPath := inputsPath.Index(i).Child("objectRef")
+		groupVersion, err := schema.ParseGroupVersion(input.ObjectRef.APIVersion)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(inputPath.Child("apiVersion"), input.ObjectRef.APIVersion, err.Error()))
+			continue
+		}
+		if groupVersion.Group == "" && input.ObjectRef.Kind == "Secret" {
+			allErrs = append(allErrs, field.Forbidden(inputPath.Child("kind"), "secrets cannot be template inputs"))
+			continue
+		}
+		if v.RESTMapper == nil {
+			continue
+		}
+		mapping, err := v.RESTMapper.RESTMapping(schema.GroupKind{Group: groupVersion.Group, Kind: input.ObjectRef.Kind}, groupVersion.Version)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(inputPath.Child("kind"), input.ObjectRef.Kind, err.Error()))
+			continue
+		}
+		attributes := authorizationv1.ResourceAttributes{
+			Verb: "get", Group: mapping.Resource.Group, Resource: mapping.Resource.Resource, Name: input.ObjectRef.Name,
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			attributes.Namespace = issue.Namespace
+		}
+		allErrs = append(allErrs, v.reviewAccess(ctx, inputPath, []authorizationv1.ResourceAttributes{attributes})...)
+	}
+	return allErrs
+}
+
+// validateDescriptionFrom rejects descriptions read from a ConfigMap or a Secret that the requesting user cannot read,
+// since the controller would publish its key to GitHub
+func (v *GithubIssueCustomValidator) validateDescriptionFrom(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	sourcePath := field.NewPath("spec", "descriptionFrom")
+	source := issue.Spec.DescriptionFrom
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		return v.reviewAccess(ctx, sourcePath.Child("configMapKeyRef"), []authorizationv1.ResourceAttributes{
+			{Verb: "get", Resource: "configmaps", Name: source.ConfigMapKeyRef.Name, Namespace: issue.Namespace},
+		})
+	case source.SecretKeyRef != nil:
+		return v.reviewAccess(ctx, sourcePath.Child("secretKeyRef"), []authorizationv1.ResourceAttributes{
+			{Verb: "get", Resource: "secrets", Name: source.SecretKeyRef.Name, Namespace: issue.Namespace},
+		})
+	}
+	return nil
+}
+
+// workloadResources maps the kinds a rolloutRestart action can target to their resources
+var workloadResources = map[string]string{
+	"Deployment":  "deployments",
+	"StatefulSet": "statefulsets",
+	"DaemonSet":   "daemonsets",
+}
+
+// validateActions rejects remediation actions that the requesting user could not run themselves
+func (v *GithubIssueCustomValidator) validateActions(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	actionsPath := field.NewPath("spec", "onPullRequestMerged")
+	for i, action := range issue.Spec.OnPullRequestMerged {
+		var required []authorizationv1.ResourceAttributes
+		switch {
+		case action.RolloutRestart != nil:
+			required = append(required, authorizationv1.ResourceAttributes{
+				Namespace: issue.Namespace, Verb: "patch", Group: "apps",
+				Resource: workloadResources[action.RolloutRestart.Kind], Name: action.RolloutRestart.Name,
+			})
+		case action.CreateJob != nil:
+			required = append(required,
+				authorizationv1.ResourceAttributes{Namespace: issue.Namespace, Verb: "get", Group: "batch", Resource: "cronjobs", Name: action.CreateJob.FromCronJob},
+				authorizationv1.ResourceAttributes{Namespace: issue.Namespace, Verb: "create", Group: "batch", Resource: "jobs"},
+			)
+		}
+		allErrs = append(allErrs, v.reviewAccess(ctx, actionsPath.Index(i), required)...)
+	}
+	return allErrs
+}
+
+// reviewAccess checks through SubjectAccessReviews that the user making the admission request holds the required permissions
+func (v *GithubIssueCustomValidator) reviewAccess(ctx context.Context, fieldPath *field.Path, required []authorizationv1.ResourceAttributes) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(required) == 0 {
+		return allErrs
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return append(allErrs, field.InternalError(fieldPath, fmt.Errorf("failed reading the requesting user: %v", err.Error())))
+	}
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	for i := range required {
+		review := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &required[i],
+			User:               req.UserInfo.Username,
+			UID:                req.UserInfo.UID,
+			Groups:             req.UserInfo.Groups,
+			Extra:              extra,
+		}}
+		if err := v.Client.Create(ctx, review); err != nil {
+			return append(allErrs, field.InternalError(fieldPath, fmt.Errorf("failed reviewing access: %v", err.Error())))
+		}
+		if !review.Status.Allowed {
+			attributes := required[i]
+			message := fmt.Sprintf("%s cannot %s %s", req.UserInfo.Username, attributes.Verb, strings.TrimSpace(attributes.Resource+" "+attributes.Name))
+			if attributes.Namespace != "" {
+				message += " in namespace " + attributes.Namespace
+			}
+			allErrs = append(allErrs, field.Forbidden(fieldPath, message))
+		}
+	}
+	return allErrs
+}
+
+// validateRepoAccess checks that the repo exists, has issues enabled and that the token can write to it
+func (v *GithubIssueCustomValidator) validateRepoAccess(ctx context.Context, issue *issuesv1.GithubIssue) field.ErrorList {
+	var allErrs field.ErrorList
+	if v.GitHubClient == nil {
+		return allErrs
+	}
+	repoPath := field.NewPath("spec", "repo")
+	owner, repo, err := repourl.Parse(issue.Spec.Repo)
+	if err != nil {
+		return allErrs
+	}
+	gitHubRepo, response, err := v.GitHubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return append(allErrs, field.NotFound(repoPath, issue.Spec.Repo))
+		}
+		return append(allErrs, field.InternalError(repoPath, fmt.Errorf("failed fetching repository: %v", err.Error())))
+	}
+	if !gitHubRepo.GetHasIssues() {
+		allErrs = append(allErrs, field.Invalid(repoPath, issue.Spec.Repo, "issues are disabled for this repository"))
+	}
+	if !gitHubRepo.GetPermissions()["push"] {
+		allErrs = append(allErrs, field.Forbidden(repoPath, "the GitHub token cannot write to this repository"))
+	}
+	return allErrs
+}
+
+func toInvalid(issue *issuesv1.GithubIssue, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(issuesv1.GroupVersion.WithKind("GithubIssue").GroupKind(), issue.Name, allErrs)
+}
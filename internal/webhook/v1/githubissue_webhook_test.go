@@ -0,0 +1,355 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newTestIssue(name string, title string) *issuesv1.GithubIssue {
+	return &issuesv1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: issuesv1.GithubIssueSpec{
+			Repo:  "https://github.com/test/test",
+			Title: title,
+		},
+	}
+}
+
+func newTestClient(objects ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(issuesv1.AddToScheme(s)).To(Succeed())
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build()
+}
+
+// newTestPolicy returns the default namespace and an ops policy allowing only test/* repos and the bug label in it
+func newTestPolicy() []client.Object {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "ops"}}}
+	policy := &issuesv1.GithubIssuePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "ops"},
+		Spec: issuesv1.GithubIssuePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+			Repos:             []string{"test/*"},
+			Labels:            []string{"bug"},
+		},
+	}
+	return []client.Object{namespace, policy}
+}
+
+func newTestValidator(objects ...client.Object) *GithubIssueCustomValidator {
+	return &GithubIssueCustomValidator{Client: newTestClient(objects...)}
+}
+
+var _ = Describe("GithubIssue validating webhook", func() {
+	ctx := context.Background()
+
+	It("accepts a well formed issue", func() {
+		issue := newTestIssue("valid", "a title")
+		issue.Spec.Labels = []string{"bug", "good first issue"}
+		issue.Spec.Assignees = []string{"octo-cat"}
+		_, err := newTestValidator().ValidateCreate(ctx, issue)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects malformed labels and assignees", func() {
+		issue := newTestIssue("invalid", "a title")
+		issue.Spec.Labels = []string{"bug", "BUG", " padded"}
+		issue.Spec.Assignees = []string{"-not-a-login"}
+		_, err := newTestValidator().ValidateCreate(ctx, issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.labels[1]"))
+		Expect(err.Error()).To(ContainSubstring("spec.labels[2]"))
+		Expect(err.Error()).To(ContainSubstring("spec.assignees[0]"))
+	})
+
+	It("rejects an issue with the same repo and title as an existing one", func() {
+		existing := newTestIssue("existing", "Same Title")
+		existing.Namespace = "other"
+		_, err := newTestValidator(existing).ValidateCreate(ctx, newTestIssue("duplicate", "same title"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("other/existing"))
+	})
+
+	It("rejects an issue with the title of an existing issue that was found by its title", func() {
+		existing := newTestIssue("existing", "Same Title")
+		existing.Status.Number = 7
+		_, err := newTestValidator(existing).ValidateCreate(ctx, newTestIssue("duplicate", "same title"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.title"))
+
+		By("letting the title of a closed issue be reused")
+		existing.Status.Conditions = []metav1.Condition{{Type: "IssueIsOpen", Status: metav1.ConditionFalse, Reason: "IssueIsOpen", LastTransitionTime: metav1.Now()}}
+		_, err = newTestValidator(existing).ValidateCreate(ctx, newTestIssue("duplicate", "same title"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects an issue bound to a number that is already managed", func() {
+		existing := newTestIssue("existing", "first")
+		existing.Status.Number = 7
+		issue := newTestIssue("duplicate", "second")
+		issue.Spec.Number = 7
+		_, err := newTestValidator(existing).ValidateCreate(ctx, issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.number"))
+	})
+
+	It("accepts issues bound by number that share a title", func() {
+		existing := newTestIssue("existing", "Weekly review")
+		existing.Status.Number = 7
+		issue := newTestIssue("imported", "weekly review")
+		issue.Spec.Number = 8
+		validator := newTestValidator(existing)
+		_, err := validator.ValidateCreate(ctx, issue)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("letting updates that keep the target through")
+		unbound := newTestIssue("unbound", "Weekly review")
+		updated := unbound.DeepCopy()
+		updated.Finalizers = []string{"issues.dvir.io/finalizer"}
+		_, err = newTestValidator(newTestIssue("other", "Weekly review"), unbound).ValidateUpdate(ctx, unbound, updated)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects changing the repo after the issue is bound", func() {
+		oldIssue := newTestIssue("bound", "a title")
+		oldIssue.Status.Number = 3
+		newIssue := oldIssue.DeepCopy()
+		newIssue.Spec.Repo = "https://github.com/test/other"
+		_, err := newTestValidator(oldIssue).ValidateUpdate(ctx, oldIssue, newIssue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("immutable"))
+
+		newIssue.Spec.Repo = oldIssue.Spec.Repo
+		newIssue.Spec.Description = "edited"
+		_, err = newTestValidator(oldIssue).ValidateUpdate(ctx, oldIssue, newIssue)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects remediation actions the requesting user cannot run", func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(issuesv1.AddToScheme(s)).To(Succeed())
+		//ops may do anything, dev may only patch deployments
+		validator := &GithubIssueCustomValidator{Client: fake.NewClientBuilder().WithScheme(s).
+			WithInterceptorFuncs(interceptor.Funcs{Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				attributes := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "ops" || (attributes.Verb == "patch" && attributes.Resource == "deployments")
+				return nil
+			}}).
+			Build()}
+		as := func(user string) context.Context {
+			return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: user},
+			}})
+		}
+
+		issue := newTestIssue("remediated", "a title")
+		issue.Spec.OnPullRequestMerged = []issuesv1.RemediationAction{
+			{RolloutRestart: &issuesv1.RolloutRestartAction{Kind: "Deployment", Name: "api"}},
+			{CreateJob: &issuesv1.CreateJobAction{FromCronJob: "backfill"}},
+		}
+		_, err := validator.ValidateCreate(as("dev"), issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.onPullRequestMerged[1]: Forbidden: dev cannot get cronjobs backfill"))
+		Expect(err.Error()).To(ContainSubstring("dev cannot create jobs in namespace default"))
+		Expect(err.Error()).ToNot(ContainSubstring("spec.onPullRequestMerged[0]"))
+
+		_, err = validator.ValidateCreate(as("ops"), issue)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("letting updates that keep the actions through")
+		updated := issue.DeepCopy()
+		updated.Finalizers = []string{"issues.dvir.io/finalizer"}
+		_, err = validator.ValidateUpdate(as("dev"), issue, updated)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects secrets and template inputs the requesting user cannot read", func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(issuesv1.AddToScheme(s)).To(Succeed())
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+		//Everyone may read configmaps, nobody may read nodes
+		validator := &GithubIssueCustomValidator{RESTMapper: mapper, Client: fake.NewClientBuilder().WithScheme(s).
+			WithInterceptorFuncs(interceptor.Funcs{Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review := obj.(*authorizationv1.SubjectAccessReview)
+				review.Status.Allowed = review.Spec.ResourceAttributes.Resource == "configmaps"
+				return nil
+			}}).
+			Build()}
+		requestCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: "dev"},
+		}})
+
+		issue := newTestIssue("templated", "{{ .app.data.name }} is down")
+		issue.Spec.TemplateInputs = []issuesv1.TemplateInput{
+			{Name: "app", ObjectRef: issuesv1.TemplateObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: "app"}},
+			{Name: "token", ObjectRef: issuesv1.TemplateObjectReference{APIVersion: "v1", Kind: "Secret", Name: "token"}},
+			{Name: "node", ObjectRef: issuesv1.TemplateObjectReference{APIVersion: "v1", Kind: "Node", Name: "worker"}},
+		}
+		_, err := validator.ValidateCreate(requestCtx, issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).ToNot(ContainSubstring("spec.templateInputs[0]"))
+		Expect(err.Error()).To(ContainSubstring("spec.templateInputs[1].objectRef.kind: Forbidden: secrets cannot be template inputs"))
+		Expect(err.Error()).To(ContainSubstring("spec.templateInputs[2].objectRef: Forbidden: dev cannot get nodes worker]"))
+
+		issue.Spec.TemplateInputs = issue.Spec.TemplateInputs[:1]
+		_, err = validator.ValidateCreate(requestCtx, issue)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects descriptions read from secrets the requesting user cannot read", func() {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(issuesv1.AddToScheme(s)).To(Succeed())
+		//Everyone may read configmaps, nobody may read secrets
+		validator := &GithubIssueCustomValidator{Client: fake.NewClientBuilder().WithScheme(s).
+			WithInterceptorFuncs(interceptor.Funcs{Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review := obj.(*authorizationv1.SubjectAccessReview)
+				review.Status.Allowed = review.Spec.ResourceAttributes.Resource == "configmaps"
+				return nil
+			}}).
+			Build()}
+		requestCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: "dev"},
+		}})
+
+		issue := newTestIssue("described", "a title")
+		issue.Spec.DescriptionFrom = &issuesv1.DescriptionSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password",
+		}}
+		_, err := validator.ValidateCreate(requestCtx, issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.descriptionFrom.secretKeyRef: Forbidden: dev cannot get secrets db in namespace default"))
+
+		By("checking the secret when an update adds it")
+		updated := newTestIssue("described", "a title")
+		_, err = validator.ValidateUpdate(requestCtx, updated, issue)
+		Expect(err).To(HaveOccurred())
+
+		issue.Spec.DescriptionFrom = &issuesv1.DescriptionSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "runbook"}, Key: "body",
+		}}
+		_, err = validator.ValidateCreate(requestCtx, issue)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects issues that the policies of the namespace do not allow", func() {
+		validator := newTestValidator(newTestPolicy()...)
+
+		issue := newTestIssue("denied", "a title")
+		issue.Spec.Repo = "https://github.com/other/repo"
+		issue.Spec.Labels = []string{"bug", "feature"}
+		_, err := validator.ValidateCreate(ctx, issue)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.repo: Forbidden: ops does not allow repo other/repo"))
+		Expect(err.Error()).To(ContainSubstring("spec.labels[1]"))
+
+		By("letting metadata updates of a denied issue through")
+		updated := issue.DeepCopy()
+		updated.Finalizers = []string{"issues.dvir.io/finalizer"}
+		_, err = validator.ValidateUpdate(ctx, issue, updated)
+		Expect(err).ToNot(HaveOccurred())
+
+		issue.Spec.Repo = "https://github.com/test/repo"
+		issue.Spec.Labels = []string{"bug"}
+		_, err = validator.ValidateCreate(ctx, issue)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("GithubIssue defaulting webhook", func() {
+	ctx := context.Background()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "ops"}}}
+
+	It("injects the conventions of matching defaults", func() {
+		teamDefaults := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: issuesv1.GithubIssueDefaultsSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+				Labels:            []string{"ops"},
+				TitlePrefix:       "[team] ",
+			},
+		}
+		ownerDefaults := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "owner"},
+			Spec: issuesv1.GithubIssueDefaultsSpec{
+				RepoOwners:  []string{"Test"},
+				Labels:      []string{"managed", "ops"},
+				Assignees:   []string{"octocat"},
+				TitlePrefix: "[owner] ",
+				BodyFooter:  "managed by {{ .Namespace }}/{{ .Name }} on {{ .Cluster }}",
+			},
+		}
+		otherOwner := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			Spec:       issuesv1.GithubIssueDefaultsSpec{RepoOwners: []string{"someone-else"}, Labels: []string{"unrelated"}},
+		}
+		defaulter := &GithubIssueCustomDefaulter{
+			Client:      newTestClient(namespace, teamDefaults, ownerDefaults, otherOwner),
+			ClusterName: "prod",
+		}
+
+		issue := newTestIssue("defaulted", "disk full")
+		issue.Spec.Description = "details"
+		Expect(defaulter.Default(ctx, issue)).To(Succeed())
+		Expect(issue.Spec.Title).To(Equal("[owner] disk full"))
+		Expect(issue.Spec.Labels).To(Equal([]string{"managed", "ops"}))
+		Expect(issue.Spec.Assignees).To(Equal([]string{"octocat"}))
+		Expect(issue.Spec.Description).To(Equal("details\n\nmanaged by default/defaulted on prod"))
+
+		By("applying the defaults again")
+		Expect(defaulter.Default(ctx, issue)).To(Succeed())
+		Expect(issue.Spec.Title).To(Equal("[owner] disk full"))
+		Expect(issue.Spec.Labels).To(HaveLen(2))
+		Expect(issue.Spec.Description).To(Equal("details\n\nmanaged by default/defaulted on prod"))
+	})
+
+	It("takes the sync interval of the most specific defaults", func() {
+		ownerDefaults := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "owner"},
+			Spec:       issuesv1.GithubIssueDefaultsSpec{RepoOwners: []string{"test"}, SyncInterval: &metav1.Duration{Duration: time.Minute}},
+		}
+		repoDefaults := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "archive"},
+			Spec:       issuesv1.GithubIssueDefaultsSpec{Repos: []string{"Test/Test"}, SyncInterval: &metav1.Duration{Duration: time.Hour}},
+		}
+		otherRepo := &issuesv1.GithubIssueDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			Spec:       issuesv1.GithubIssueDefaultsSpec{Repos: []string{"test/other"}, Labels: []string{"unrelated"}},
+		}
+		defaulter := &GithubIssueCustomDefaulter{Client: newTestClient(namespace, ownerDefaults, repoDefaults, otherRepo)}
+
+		issue := newTestIssue("archived", "old report")
+		Expect(defaulter.Default(ctx, issue)).To(Succeed())
+		Expect(issue.Spec.SyncInterval.Duration).To(Equal(time.Hour))
+		Expect(issue.Spec.Labels).To(BeEmpty())
+
+		By("keeping the interval set on the issue")
+		issue.Spec.SyncInterval = &metav1.Duration{Duration: 30 * time.Second}
+		Expect(defaulter.Default(ctx, issue)).To(Succeed())
+		Expect(issue.Spec.SyncInterval.Duration).To(Equal(30 * time.Second))
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/policy"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var githubissuecommentlog = logf.Log.WithName("githubissuecomment-resource")
+
+// SetupGithubIssueCommentWebhookWithManager registers the GithubIssueComment validating webhook with the manager.
+func SetupGithubIssueCommentWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&issuesv1.GithubIssueComment{}).
+		WithValidator(&GithubIssueCommentCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-issues-dvir-io-v1-githubissuecomment,mutating=false,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githubissuecomments,verbs=create;update,versions=v1,name=vgithubissuecomment.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// GithubIssueCommentCustomValidator rejects comments on repos that the policies of their namespace do not allow
+type GithubIssueCommentCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &GithubIssueCommentCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCommentCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	comment, ok := obj.(*issuesv1.GithubIssueComment)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssueComment object but got %T", obj)
+	}
+	githubissuecommentlog.Info("validate create", "name", comment.Name)
+	return nil, v.validate(ctx, comment)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCommentCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldComment, ok := oldObj.(*issuesv1.GithubIssueComment)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssueComment object but got %T", oldObj)
+	}
+	comment, ok := newObj.(*issuesv1.GithubIssueComment)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssueComment object but got %T", newObj)
+	}
+	githubissuecommentlog.Info("validate update", "name", comment.Name)
+
+	// Metadata updates, such as the finalizers of the controller, are let through comments denied by a newer policy
+	if !comment.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldComment.Spec, comment.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, comment)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCommentCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the repo of the comment, or of the GithubIssue it references, against the policies of its namespace
+func (v *GithubIssueCommentCustomValidator) validate(ctx context.Context, comment *issuesv1.GithubIssueComment) error {
+	specPath := field.NewPath("spec")
+	repoPath, repoURL := specPath.Child("repo"), comment.Spec.Repo
+	if comment.Spec.IssueRef != nil {
+		issue := &issuesv1.GithubIssue{}
+		if err := v.Client.Get(ctx, types.NamespacedName{Namespace: comment.Namespace, Name: comment.Spec.IssueRef.Name}, issue); err != nil {
+			// The controller reports references to missing issues, the issues themselves are checked on admission
+			return client.IgnoreNotFound(err)
+		}
+		repoPath, repoURL = specPath.Child("issueRef"), issue.Spec.Repo
+	}
+	allErrs := policyErrors(policy.CheckTarget(ctx, v.Client, comment.Namespace, repoURL, nil), specPath, repoPath, specPath)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(issuesv1.GroupVersion.WithKind("GithubIssueComment").GroupKind(), comment.Name, allErrs)
+}
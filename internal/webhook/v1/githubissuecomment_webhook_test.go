@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"context"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GithubIssueComment validating webhook", func() {
+	ctx := context.Background()
+
+	It("rejects comments on repos that the policies of the namespace do not allow", func() {
+		denied := newTestIssue("denied", "a title")
+		denied.Spec.Repo = "https://github.com/other/repo"
+		validator := &GithubIssueCommentCustomValidator{Client: newTestClient(append(newTestPolicy(), denied)...)}
+
+		comment := &issuesv1.GithubIssueComment{
+			ObjectMeta: metav1.ObjectMeta{Name: "comment", Namespace: "default"},
+			Spec:       issuesv1.GithubIssueCommentSpec{Repo: "https://github.com/other/repo", Number: 1, Body: "hello"},
+		}
+		_, err := validator.ValidateCreate(ctx, comment)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.repo: Forbidden: ops does not allow repo other/repo"))
+
+		By("checking the repo of the referenced issue")
+		byRef := comment.DeepCopy()
+		byRef.Spec = issuesv1.GithubIssueCommentSpec{IssueRef: &issuesv1.IssueReference{Name: "denied"}, Body: "hello"}
+		_, err = validator.ValidateCreate(ctx, byRef)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.issueRef: Forbidden"))
+
+		By("letting references to missing issues through")
+		byRef.Spec.IssueRef.Name = "missing"
+		_, err = validator.ValidateCreate(ctx, byRef)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("letting metadata updates of a denied comment through")
+		updated := comment.DeepCopy()
+		updated.Finalizers = []string{"issues.dvir.io/finalizer"}
+		_, err = validator.ValidateUpdate(ctx, comment, updated)
+		Expect(err).ToNot(HaveOccurred())
+
+		comment.Spec.Repo = "https://github.com/test/repo"
+		_, err = validator.ValidateCreate(ctx, comment)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
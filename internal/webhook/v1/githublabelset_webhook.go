@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/policy"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var githublabelsetlog = logf.Log.WithName("githublabelset-resource")
+
+// SetupGithubLabelSetWebhookWithManager registers the GithubLabelSet validating webhook with the manager.
+func SetupGithubLabelSetWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&issuesv1.GithubLabelSet{}).
+		WithValidator(&GithubLabelSetCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-issues-dvir-io-v1-githublabelset,mutating=false,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githublabelsets,verbs=create;update,versions=v1,name=vgithublabelset.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// GithubLabelSetCustomValidator rejects label sets of repos, or with labels, that the policies of their namespace do not allow
+type GithubLabelSetCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &GithubLabelSetCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubLabelSetCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	labelSet, ok := obj.(*issuesv1.GithubLabelSet)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubLabelSet object but got %T", obj)
+	}
+	githublabelsetlog.Info("validate create", "name", labelSet.Name)
+	return nil, v.validate(ctx, labelSet)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubLabelSetCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldLabelSet, ok := oldObj.(*issuesv1.GithubLabelSet)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubLabelSet object but got %T", oldObj)
+	}
+	labelSet, ok := newObj.(*issuesv1.GithubLabelSet)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubLabelSet object but got %T", newObj)
+	}
+	githublabelsetlog.Info("validate update", "name", labelSet.Name)
+
+	// Metadata updates are let through label sets denied by a newer policy
+	if !labelSet.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldLabelSet.Spec, labelSet.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, labelSet)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubLabelSetCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the repo and the names of the labels against the policies of the namespace
+func (v *GithubLabelSetCustomValidator) validate(ctx context.Context, labelSet *issuesv1.GithubLabelSet) error {
+	names := make([]string, 0, len(labelSet.Spec.Labels))
+	for _, label := range labelSet.Spec.Labels {
+		names = append(names, label.Name)
+	}
+	specPath := field.NewPath("spec")
+	err := policy.CheckTarget(ctx, v.Client, labelSet.Namespace, labelSet.Spec.Repo, names)
+	allErrs := policyErrors(err, specPath, specPath.Child("repo"), specPath.Child("labels"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(issuesv1.GroupVersion.WithKind("GithubLabelSet").GroupKind(), labelSet.Name, allErrs)
+}
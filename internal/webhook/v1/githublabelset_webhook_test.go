@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"context"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GithubLabelSet validating webhook", func() {
+	ctx := context.Background()
+
+	It("rejects label sets that the policies of the namespace do not allow", func() {
+		validator := &GithubLabelSetCustomValidator{Client: newTestClient(newTestPolicy()...)}
+
+		labelSet := &issuesv1.GithubLabelSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "labels", Namespace: "default"},
+			Spec: issuesv1.GithubLabelSetSpec{
+				Repo:   "https://github.com/other/repo",
+				Labels: []issuesv1.LabelSpec{{Name: "bug"}, {Name: "feature"}},
+			},
+		}
+		_, err := validator.ValidateCreate(ctx, labelSet)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.repo: Forbidden: ops does not allow repo other/repo"))
+		Expect(err.Error()).To(ContainSubstring("spec.labels[1]"))
+
+		By("letting metadata updates of a denied label set through")
+		updated := labelSet.DeepCopy()
+		updated.Finalizers = []string{"issues.dvir.io/finalizer"}
+		_, err = validator.ValidateUpdate(ctx, labelSet, updated)
+		Expect(err).ToNot(HaveOccurred())
+
+		labelSet.Spec.Repo = "https://github.com/test/repo"
+		labelSet.Spec.Labels = labelSet.Spec.Labels[:1]
+		_, err = validator.ValidateCreate(ctx, labelSet)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
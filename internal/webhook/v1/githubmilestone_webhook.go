@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	"dvir.io/githubissue/internal/policy"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var githubmilestonelog = logf.Log.WithName("githubmilestone-resource")
+
+// SetupGithubMilestoneWebhookWithManager registers the GithubMilestone validating webhook with the manager.
+func SetupGithubMilestoneWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&issuesv1.GithubMilestone{}).
+		WithValidator(&GithubMilestoneCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-issues-dvir-io-v1-githubmilestone,mutating=false,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githubmilestones,verbs=create;update,versions=v1,name=vgithubmilestone.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=issues.dvir.io,resources=githubissuepolicies,verbs=get;list;watch
+
+// GithubMilestoneCustomValidator rejects milestones in repos that the policies of their namespace do not allow
+type GithubMilestoneCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &GithubMilestoneCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubMilestoneCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	milestone, ok := obj.(*issuesv1.GithubMilestone)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubMilestone object but got %T", obj)
+	}
+	githubmilestonelog.Info("validate create", "name", milestone.Name)
+	return nil, v.validate(ctx, milestone)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubMilestoneCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldMilestone, ok := oldObj.(*issuesv1.GithubMilestone)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubMilestone object but got %T", oldObj)
+	}
+	milestone, ok := newObj.(*issuesv1.GithubMilestone)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubMilestone object but got %T", newObj)
+	}
+	githubmilestonelog.Info("validate update", "name", milestone.Name)
+
+	// Only the repo is checked, other updates are let through milestones denied by a newer policy
+	if !milestone.DeletionTimestamp.IsZero() || oldMilestone.Spec.Repo == milestone.Spec.Repo {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, milestone)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubMilestoneCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the repo of the milestone against the policies of the namespace
+func (v *GithubMilestoneCustomValidator) validate(ctx context.Context, milestone *issuesv1.GithubMilestone) error {
+	specPath := field.NewPath("spec")
+	err := policy.CheckTarget(ctx, v.Client, milestone.Namespace, milestone.Spec.Repo, nil)
+	allErrs := policyErrors(err, specPath, specPath.Child("repo"), specPath)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(issuesv1.GroupVersion.WithKind("GithubMilestone").GroupKind(), milestone.Name, allErrs)
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"context"
+
+	issuesv1 "dvir.io/githubissue/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GithubMilestone validating webhook", func() {
+	ctx := context.Background()
+
+	It("rejects milestones in repos that the policies of the namespace do not allow", func() {
+		validator := &GithubMilestoneCustomValidator{Client: newTestClient(newTestPolicy()...)}
+
+		milestone := &issuesv1.GithubMilestone{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "default"},
+			Spec:       issuesv1.GithubMilestoneSpec{Repo: "https://github.com/other/repo", Title: "v1.0"},
+		}
+		_, err := validator.ValidateCreate(ctx, milestone)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.repo: Forbidden: ops does not allow repo other/repo"))
+
+		By("letting updates that keep the repo through")
+		updated := milestone.DeepCopy()
+		updated.Spec.Title = "v1.1"
+		_, err = validator.ValidateUpdate(ctx, milestone, updated)
+		Expect(err).ToNot(HaveOccurred())
+
+		milestone.Spec.Repo = "https://github.com/test/repo"
+		_, err = validator.ValidateCreate(ctx, milestone)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	issuesv2 "dvir.io/githubissue/api/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var githubissuelog = logf.Log.WithName("githubissue-resource")
+
+// defaultHost is the host of repository references that leave it unset
+const defaultHost = "github.com"
+
+// SetupGithubIssueWebhookWithManager registers the GithubIssue conversion and validating webhooks with the manager.
+// v2 is the hub, every other version served by the CRD is converted through it.
+// host is the host of the GitHub instance that the manager files issues on.
+func SetupGithubIssueWebhookWithManager(mgr ctrl.Manager, host string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&issuesv2.GithubIssue{}).
+		WithValidator(&GithubIssueCustomValidator{Host: host}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-issues-dvir-io-v2-githubissue,mutating=false,failurePolicy=fail,sideEffects=None,groups=issues.dvir.io,resources=githubissues,verbs=create;update,versions=v2,name=vgithubissue-v2.kb.io,admissionReviewVersions=v1
+
+// GithubIssueCustomValidator rejects GithubIssue objects in repositories of another GitHub instance than the one
+// the manager talks to, since their issues would otherwise be filed on the wrong instance
+type GithubIssueCustomValidator struct {
+	//Host of the GitHub instance of the manager, github.com when empty
+	Host string
+}
+
+var _ webhook.CustomValidator = &GithubIssueCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	issue, ok := obj.(*issuesv2.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", obj)
+	}
+	githubissuelog.Info("validate create", "name", issue.Name)
+	return nil, v.validate(issue)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldIssue, ok := oldObj.(*issuesv2.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", oldObj)
+	}
+	issue, ok := newObj.(*issuesv2.GithubIssue)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubIssue object but got %T", newObj)
+	}
+	githubissuelog.Info("validate update", "name", issue.Name)
+
+	// Only changes of the host are checked, so the controller can still remove the finalizers of existing issues
+	if !issue.DeletionTimestamp.IsZero() || hostOf(oldIssue.Spec.Repo) == hostOf(issue.Spec.Repo) {
+		return nil, nil
+	}
+	return nil, v.validate(issue)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *GithubIssueCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the host of the repository against the GitHub instance of the manager
+func (v *GithubIssueCustomValidator) validate(issue *issuesv2.GithubIssue) error {
+	host := v.Host
+	if host == "" {
+		host = defaultHost
+	}
+	if strings.EqualFold(hostOf(issue.Spec.Repo), host) {
+		return nil
+	}
+	hostPath := field.NewPath("spec", "repo", "host")
+	allErrs := field.ErrorList{field.NotSupported(hostPath, issue.Spec.Repo.Host, []string{host})}
+	return apierrors.NewInvalid(issuesv2.GroupVersion.WithKind("GithubIssue").GroupKind(), issue.Name, allErrs)
+}
+
+func hostOf(repo issuesv2.RepositoryRef) string {
+	if repo.Host == "" {
+		return defaultHost
+	}
+	return repo.Host
+}
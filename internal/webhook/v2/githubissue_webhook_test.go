@@ -0,0 +1,55 @@
+package v2
+
+import (
+	"context"
+
+	issuesv2 "dvir.io/githubissue/api/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GithubIssue validating webhook", func() {
+	ctx := context.Background()
+
+	newIssue := func(host string) *issuesv2.GithubIssue {
+		return &issuesv2.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+			Spec: issuesv2.GithubIssueSpec{
+				Repo:  issuesv2.RepositoryRef{Host: host, Owner: "test", Name: "test"},
+				Title: "a title",
+			},
+		}
+	}
+
+	DescribeTable("checking the host of the repository",
+		func(configured string, host string, message string) {
+			validator := &GithubIssueCustomValidator{Host: configured}
+			_, err := validator.ValidateCreate(ctx, newIssue(host))
+			if message == "" {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			}
+		},
+		Entry("accepts github.com by default", "", "github.com", ""),
+		Entry("accepts an unset host", "", "", ""),
+		Entry("ignores the case of the host", "ghe.example.com", "GHE.example.com", ""),
+		Entry("rejects another host", "", "ghe.example.com", `spec.repo.host: Unsupported value: "ghe.example.com": supported values: "github.com"`),
+		Entry("rejects github.com on an enterprise instance", "ghe.example.com", "github.com", `supported values: "ghe.example.com"`),
+	)
+
+	It("only checks updates that change the host", func() {
+		validator := &GithubIssueCustomValidator{Host: "github.com"}
+		issue := newIssue("ghe.example.com")
+
+		updated := issue.DeepCopy()
+		updated.Spec.Title = "another title"
+		_, err := validator.ValidateUpdate(ctx, issue, updated)
+		Expect(err).ToNot(HaveOccurred())
+
+		moved := newIssue("github.com")
+		_, err = validator.ValidateUpdate(ctx, moved, issue)
+		Expect(err).To(HaveOccurred())
+	})
+})